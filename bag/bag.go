@@ -0,0 +1,239 @@
+// Package bag implements bagging: a majority vote of classifiers trained on
+// bootstrap samples of the training points.
+package bag
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Model is a trained member of a bag.
+type Model interface {
+	// Predictions returns the predictions, +1 or -1, of the points passed
+	// in, without labels.
+	Predictions(x [][]float64) ([]float64, error)
+}
+
+// Bag is a bagging classifier. Each of its members is trained by Fit on a
+// bootstrap sample of the training points and the bag predicts the label
+// of the majority of their votes, +1 on ties. If the training points have
+// weights, each of them is drawn in the bootstrap samples with a
+// probability proportional to its weight.
+// x0 = 1 is left out of the points passed to the members.
+type Bag struct {
+	classify.Data
+	Bags int   // number of members.
+	Seed int64 // seed of the bootstrap samples.
+
+	// Fit returns a member trained on the points passed in, the label
+	// last. It is called from several goroutines.
+	Fit func(data [][]float64) (Model, error)
+
+	Seeds   []int64 // seed of the bootstrap sample of each member.
+	Members []Model
+}
+
+// NewBag returns a bag of 25 members with seed 1 and 10 folds.
+func NewBag() *Bag {
+	return &Bag{
+		Data: classify.Data{Folds: 10},
+		Bags: 25,
+		Seed: 1,
+	}
+}
+
+// Learn trains the members in parallel.
+func (b *Bag) Learn() error {
+	b.ResetErrors()
+	b.Members = nil
+	if b.Bags < 1 {
+		return fmt.Errorf("invalid number of bags %v", b.Bags)
+	}
+	if b.Fit == nil {
+		return fmt.Errorf("no function to fit the members")
+	}
+	if len(b.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+	r := rand.New(rand.NewSource(b.Seed))
+	b.Seeds = make([]int64, b.Bags)
+	for i := range b.Seeds {
+		b.Seeds[i] = r.Int63()
+	}
+
+	members := make([]Model, b.Bags)
+	errs := make([]error, b.Bags)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				members[i], errs[i] = b.fit(i)
+			}
+		}()
+	}
+	for i := range members {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	b.Members = members
+	return nil
+}
+
+// fit returns the member i trained on its bootstrap sample.
+func (b *Bag) fit(i int) (Model, error) {
+	return b.Fit(b.Sample(i))
+}
+
+// Sample returns the points the member i is trained on, the label last:
+// its bootstrap sample drawn from the training points with Seeds[i].
+func (b *Bag) Sample(i int) [][]float64 {
+	rows := b.sample(i)
+	data := make([][]float64, len(rows))
+	for j, row := range rows {
+		data[j] = append(append([]float64{}, b.Xn[row][1:]...), b.Yn[row])
+	}
+	return data
+}
+
+// sample returns the rows of the bootstrap sample of the member i.
+func (b *Bag) sample(i int) []int {
+	n := len(b.Xn)
+	r := rand.New(rand.NewSource(b.Seeds[i]))
+	rows := make([]int, n)
+	if b.Wn == nil {
+		for j := range rows {
+			rows[j] = r.Intn(n)
+		}
+		return rows
+	}
+	cumulative := make([]float64, n)
+	var total float64
+	for j, w := range b.Wn {
+		total += w
+		cumulative[j] = total
+	}
+	for j := range rows {
+		// the first row whose cumulative weight exceeds the draw.
+		draw := r.Float64() * total
+		rows[j] = sort.Search(n, func(k int) bool { return cumulative[k] > draw })
+	}
+	return rows
+}
+
+// votes returns the predictions of each member for x, points without x0.
+func (b *Bag) votes(x [][]float64) ([][]float64, error) {
+	votes := make([][]float64, len(b.Members))
+	for i, m := range b.Members {
+		var err error
+		if votes[i], err = m.Predictions(x); err != nil {
+			return nil, err
+		}
+	}
+	return votes, nil
+}
+
+// points returns the training points without x0.
+func (b *Bag) points() [][]float64 {
+	x := make([][]float64, len(b.Xn))
+	for j := range b.Xn {
+		x[j] = b.Xn[j][1:]
+	}
+	return x
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (b *Bag) Predictions(x [][]float64) ([]float64, error) {
+	votes, err := b.votes(x)
+	if err != nil {
+		return nil, err
+	}
+	predictions := make([]float64, len(x))
+	for j := range x {
+		var vote float64
+		for i := range votes {
+			vote += votes[i][j]
+		}
+		predictions[j] = classify.Sign(vote)
+	}
+	return predictions, nil
+}
+
+// Ein returns the in sample error.
+func (b *Bag) Ein() float64 {
+	return b.CachedEin(func() float64 {
+		if len(b.Members) == 0 || len(b.Xn) == 0 {
+			return 1
+		}
+		predictions, err := b.Predictions(b.points())
+		if err != nil {
+			return 1
+		}
+		var wrong int
+		for j, p := range predictions {
+			if p != b.Yn[j] {
+				wrong++
+			}
+		}
+		return float64(wrong) / float64(len(b.Xn))
+	})
+}
+
+// Ecv returns the out of bag error: each training point is predicted by the
+// vote of the members whose bootstrap sample does not hold it. Points in
+// every sample are not counted.
+func (b *Bag) Ecv() float64 {
+	return b.CachedEcv(func() float64 {
+		if len(b.Members) == 0 || len(b.Seeds) != len(b.Members) {
+			return 1
+		}
+		votes, err := b.votes(b.points())
+		if err != nil {
+			return 1
+		}
+		n := len(b.Xn)
+		inBag := make([][]bool, len(b.Members))
+		for i := range b.Members {
+			inBag[i] = make([]bool, n)
+			for _, j := range b.sample(i) {
+				inBag[i][j] = true
+			}
+		}
+		var wrong, counted int
+		for j := range b.Xn {
+			var vote float64
+			var voters int
+			for i := range b.Members {
+				if !inBag[i][j] {
+					vote += votes[i][j]
+					voters++
+				}
+			}
+			if voters == 0 {
+				continue
+			}
+			counted++
+			if classify.Sign(vote) != b.Yn[j] {
+				wrong++
+			}
+		}
+		if counted == 0 {
+			return 1
+		}
+		return float64(wrong) / float64(counted)
+	})
+}
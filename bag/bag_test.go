@@ -0,0 +1,99 @@
+package bag
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// stump predicts +1 when the first coordinate is above its threshold.
+type stump struct {
+	threshold float64
+}
+
+func (s stump) Predictions(x [][]float64) ([]float64, error) {
+	p := make([]float64, len(x))
+	for i := range x {
+		p[i] = -1
+		if x[i][0] > s.threshold {
+			p[i] = 1
+		}
+	}
+	return p, nil
+}
+
+// fitStump returns the stump with the lowest error on data.
+func fitStump(data [][]float64) (Model, error) {
+	best, wrong := stump{}, len(data)+1
+	for _, candidate := range data {
+		s := stump{candidate[0]}
+		p, _ := s.Predictions(data)
+		var w int
+		for i, row := range data {
+			if p[i] != row[len(row)-1] {
+				w++
+			}
+		}
+		if w < wrong {
+			best, wrong = s, w
+		}
+	}
+	return best, nil
+}
+
+// points returns points labeled +1 when their first coordinate is above
+// 0.5, with 10% of the labels flipped.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		a, b := r.Float64(), r.Float64()
+		y := -1.0
+		if a > 0.5 {
+			y = 1
+		}
+		if r.Float64() < 0.1 {
+			y = -y
+		}
+		data = append(data, []float64{a, b, y})
+	}
+	return
+}
+
+func TestBagLearn(t *testing.T) {
+	b := NewBag()
+	b.Fit = fitStump
+	if err := b.InitializeFromData(points()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Members) != 25 {
+		t.Errorf("got %v members, want 25", len(b.Members))
+	}
+	if ein := b.Ein(); ein > 0.15 {
+		t.Errorf("Ein = %v, want at most 0.15", ein)
+	}
+	if ecv := b.Ecv(); ecv > 0.2 {
+		t.Errorf("out of bag error = %v, want at most 0.2", ecv)
+	}
+	predictions, err := b.Predictions([][]float64{{0.9, 0}, {0.1, 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if predictions[0] != 1 || predictions[1] != -1 {
+		t.Errorf("wrong predictions %v", predictions)
+	}
+
+	c := NewBag()
+	c.Fit = fitStump
+	c.InitializeFromData(points())
+	c.Learn()
+	if c.Ecv() != b.Ecv() {
+		t.Errorf("two bags of the same seed have different out of bag errors %v and %v", b.Ecv(), c.Ecv())
+	}
+
+	c.Bags = 0
+	if err := c.Learn(); err == nil {
+		t.Error("no error for a bag without members")
+	}
+}
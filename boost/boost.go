@@ -0,0 +1,318 @@
+// Package boost implements a gradient boosted trees binary classifier.
+package boost
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Boost is a gradient boosting classifier with the logistic loss.
+// Its score of a point is the log odds of the label +1: F0 plus the sum of
+// the values of the leaves of the point in each regression tree, each tree
+// fitted on the gradient of the loss of the previous trees.
+// The loss of each training point is scaled by its weight, if any.
+type Boost struct {
+	classify.Data
+	Rounds        int     // maximum number of trees.
+	LearningRate  float64 // shrinkage of the value of each tree.
+	MaxDepth      int     // maximum depth of the trees.
+	MinLeaf       int     // minimum number of training points in a leaf.
+	Subsample     float64 // fraction of the training points drawn, without replacement, to fit each tree.
+	EarlyStopping int     // rounds without improvement of the validation loss before stopping, 0 disables it.
+	Seed          int64   // seed of the subsamples.
+
+	F0     float64 // initial score, the log odds of the training labels.
+	Stages []*Node // learned trees.
+}
+
+// Node is a node of a regression tree.
+// Inner nodes send the points with x[Feature] <= Threshold to Left and
+// the others to Right. Leaves have no children and add Value to the score.
+type Node struct {
+	Feature   int     `json:",omitempty"`
+	Threshold float64 `json:",omitempty"`
+	Left      *Node   `json:",omitempty"`
+	Right     *Node   `json:",omitempty"`
+	Value     float64 `json:",omitempty"`
+}
+
+// NewBoost returns a gradient boosting classifier of 100 trees of depth 3
+// with at least 5 points per leaf, learning rate 0.1, no subsampling, no
+// early stopping, seed 1 and 10 folds of cross validation.
+func NewBoost() *Boost {
+	return &Boost{
+		Data:         classify.Data{Folds: 10},
+		Rounds:       100,
+		LearningRate: 0.1,
+		MaxDepth:     3,
+		MinLeaf:      5,
+		Subsample:    1,
+		Seed:         1,
+	}
+}
+
+// Learn fits the trees one round after another.
+// With early stopping the points of the first fold are held out for
+// validation and only the rounds up to the lowest validation loss are kept.
+func (b *Boost) Learn() error {
+	b.ResetErrors()
+	b.Stages = nil
+	if b.Rounds < 1 {
+		return fmt.Errorf("invalid number of rounds %v", b.Rounds)
+	}
+	if b.LearningRate <= 0 {
+		return fmt.Errorf("invalid learning rate %v", b.LearningRate)
+	}
+	if b.MaxDepth < 1 || b.MinLeaf < 1 {
+		return fmt.Errorf("invalid tree size, depth %v and min leaf %v", b.MaxDepth, b.MinLeaf)
+	}
+	if b.Subsample <= 0 || b.Subsample > 1 {
+		return fmt.Errorf("invalid subsample %v", b.Subsample)
+	}
+	if b.EarlyStopping < 0 {
+		return fmt.Errorf("invalid early stopping %v", b.EarlyStopping)
+	}
+	if len(b.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+
+	var train, validation []int
+	if b.EarlyStopping > 0 {
+		folds := classify.Folds(len(b.Xn), b.Folds)
+		validation = folds[0]
+		for _, fold := range folds[1:] {
+			train = append(train, fold...)
+		}
+	} else {
+		for i := range b.Xn {
+			train = append(train, i)
+		}
+	}
+
+	var positives, total float64
+	for _, i := range train {
+		total += b.Weight(i)
+		if b.Yn[i] == 1 {
+			positives += b.Weight(i)
+		}
+	}
+	// the log odds are bounded so that a single class still learns.
+	p := math.Min(math.Max(positives/math.Max(total, 1e-12), 1e-6), 1-1e-6)
+	b.F0 = math.Log(p / (1 - p))
+
+	score := make([]float64, len(b.Xn))
+	for i := range score {
+		score[i] = b.F0
+	}
+	sorted := b.presort()
+	r := rand.New(rand.NewSource(b.Seed))
+	best, bestRounds, since := b.loss(validation, score), 0, 0
+	for round := 0; round < b.Rounds; round++ {
+		rows := b.sample(r, train)
+		residual := make([]float64, len(b.Xn))
+		hessian := make([]float64, len(b.Xn))
+		in := make([]bool, len(b.Xn))
+		for _, i := range rows {
+			p := sigmoid(score[i])
+			residual[i] = b.Weight(i) * (target(b.Yn[i]) - p)
+			hessian[i] = b.Weight(i) * p * (1 - p)
+			in[i] = true
+		}
+		n := b.grow(filter(sorted, in, len(rows)), residual, hessian, 0)
+		b.Stages = append(b.Stages, n)
+		for i, x := range b.Xn {
+			score[i] += n.value(x)
+		}
+		if b.EarlyStopping == 0 {
+			continue
+		}
+		if loss := b.loss(validation, score); loss < best {
+			best, bestRounds, since = loss, round+1, 0
+		} else if since++; since >= b.EarlyStopping {
+			break
+		}
+	}
+	if b.EarlyStopping > 0 {
+		b.Stages = b.Stages[:bestRounds]
+	}
+	return nil
+}
+
+// sample returns a subsample of the rows passed in.
+func (b *Boost) sample(r *rand.Rand, rows []int) []int {
+	if b.Subsample >= 1 {
+		return rows
+	}
+	n := int(math.Ceil(b.Subsample * float64(len(rows))))
+	s := make([]int, n)
+	for k, j := range r.Perm(len(rows))[:n] {
+		s[k] = rows[j]
+	}
+	return s
+}
+
+// presort returns the indexes of the training points sorted by each coordinate.
+func (b *Boost) presort() [][]int {
+	sorted := make([][]int, b.VectorSize)
+	for j := range sorted {
+		rows := make([]int, len(b.Xn))
+		for i := range rows {
+			rows[i] = i
+		}
+		sort.SliceStable(rows, func(a, c int) bool { return b.Xn[rows[a]][j] < b.Xn[rows[c]][j] })
+		sorted[j] = rows
+	}
+	return sorted
+}
+
+// filter returns the sorted indexes passed in that are in the set in,
+// keeping their order. size is the number of indexes in the set.
+func filter(sorted [][]int, in []bool, size int) [][]int {
+	f := make([][]int, len(sorted))
+	for j, rows := range sorted {
+		f[j] = make([]int, 0, size)
+		for _, i := range rows {
+			if in[i] {
+				f[j] = append(f[j], i)
+			}
+		}
+	}
+	return f
+}
+
+// loss returns the weighted mean logistic loss of the rows passed in.
+func (b *Boost) loss(rows []int, score []float64) float64 {
+	var l, total float64
+	for _, i := range rows {
+		l += b.Weight(i) * math.Log(1+math.Exp(-b.Yn[i]*score[i]))
+		total += b.Weight(i)
+	}
+	if total == 0 {
+		return 0
+	}
+	return l / total
+}
+
+// grow returns the regression tree fitted on the residuals of the points
+// of sorted, their indexes sorted by each coordinate.
+// Splits minimize the weighted squared error of the residuals and each leaf
+// holds the Newton step of its points, shrunk by the learning rate.
+// The residuals and the hessians are already scaled by the weights.
+func (b *Boost) grow(sorted [][]int, residual, hessian []float64, depth int) *Node {
+	rows := sorted[0]
+	var sum, h, weight float64
+	for _, i := range rows {
+		sum += residual[i]
+		h += hessian[i]
+		weight += b.Weight(i)
+	}
+	leaf := &Node{Value: b.LearningRate * sum / math.Max(h, 1e-9)}
+	if depth >= b.MaxDepth || len(rows) < 2*b.MinLeaf {
+		return leaf
+	}
+
+	n := &Node{}
+	best := sum * sum / math.Max(weight, 1e-12)
+	split := false
+	for j, rows := range sorted {
+		var left, leftWeight float64
+		for k := 1; k < len(rows); k++ {
+			left += residual[rows[k-1]]
+			leftWeight += b.Weight(rows[k-1])
+			lo, hi := b.Xn[rows[k-1]][j], b.Xn[rows[k]][j]
+			if lo == hi || k < b.MinLeaf || len(rows)-k < b.MinLeaf {
+				continue
+			}
+			right, rightWeight := sum-left, weight-leftWeight
+			gain := left*left/math.Max(leftWeight, 1e-12) + right*right/math.Max(rightWeight, 1e-12)
+			if gain > best+1e-12 {
+				best = gain
+				n.Feature = j
+				n.Threshold = (lo + hi) / 2
+				split = true
+			}
+		}
+	}
+	if !split {
+		return leaf
+	}
+	in := make([]bool, len(b.Xn))
+	left := 0
+	for _, i := range rows {
+		in[i] = b.Xn[i][n.Feature] <= n.Threshold
+		if in[i] {
+			left++
+		}
+	}
+	n.Left = b.grow(filter(sorted, in, left), residual, hessian, depth+1)
+	for _, i := range rows {
+		in[i] = !in[i]
+	}
+	n.Right = b.grow(filter(sorted, in, len(rows)-left), residual, hessian, depth+1)
+	return n
+}
+
+// value returns the value of the leaf of x.
+func (n *Node) value(x []float64) float64 {
+	for n.Left != nil && n.Right != nil {
+		if x[n.Feature] <= n.Threshold {
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	return n.Value
+}
+
+// Score returns the log odds of the label +1 for x.
+func (b *Boost) Score(x []float64) float64 {
+	s := b.F0
+	for _, n := range b.Stages {
+		s += n.value(x)
+	}
+	return s
+}
+
+// Predict returns +1 if the probability of the label +1 for x is at least 1/2.
+func (b *Boost) Predict(x []float64) float64 {
+	return classify.Sign(b.Score(x))
+}
+
+// Probability returns the probability of the label +1 for x.
+func (b *Boost) Probability(x []float64) float64 {
+	return classify.Logistic(b.Score(x))
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (b *Boost) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(b, &b.Data, x)
+}
+
+// Ein returns the in sample error.
+func (b *Boost) Ein() float64 {
+	return b.CachedEin(func() float64 { return classify.Ein(b, &b.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (b *Boost) Ecv() float64 {
+	return b.CachedEcv(func() float64 {
+		return classify.CrossValidation(&b.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *b
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
+
+func sigmoid(s float64) float64 {
+	return 1 / (1 + math.Exp(-s))
+}
+
+// target returns the label y as a probability: 1 for +1 and 0 for -1.
+func target(y float64) float64 {
+	return (y + 1) / 2
+}
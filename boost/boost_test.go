@@ -0,0 +1,64 @@
+package boost
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns noisy points labeled +1 when both coordinates are positive.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 300; i++ {
+		a, b := r.Float64()*4-2, r.Float64()*4-2
+		y := -1.0
+		if a > 0 && b > 0 {
+			y = 1
+		}
+		if r.Float64() < 0.05 {
+			y = -y
+		}
+		data = append(data, []float64{a, b, y})
+	}
+	return
+}
+
+func TestBoostLearn(t *testing.T) {
+	b := NewBoost()
+	if err := b.InitializeFromData(points()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Stages) != b.Rounds {
+		t.Errorf("got %v trees, want %v", len(b.Stages), b.Rounds)
+	}
+	if ein := b.Ein(); ein > 0.08 {
+		t.Errorf("Ein = %v, want at most 0.08", ein)
+	}
+	predictions, err := b.Predictions([][]float64{{1.5, 1.5}, {-1.5, 1.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if predictions[0] != 1 || predictions[1] != -1 {
+		t.Errorf("wrong predictions %v", predictions)
+	}
+}
+
+func TestBoostEarlyStopping(t *testing.T) {
+	b := NewBoost()
+	b.Rounds = 1000
+	b.LearningRate = 0.5
+	b.Subsample = 0.8
+	b.EarlyStopping = 10
+	b.InitializeFromData(points())
+	if err := b.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Stages) == 0 || len(b.Stages) >= 1000 {
+		t.Errorf("got %v trees, expected early stopping", len(b.Stages))
+	}
+	if ecv := b.Ecv(); ecv > 0.15 {
+		t.Errorf("Ecv = %v, want at most 0.15", ecv)
+	}
+}
@@ -0,0 +1,260 @@
+// Package classify holds the training data and the error measures shared by
+// the binary classifiers of this repository.
+// As in the models of github.com/santiaago/ml, labels are -1 or +1 and every
+// point starts with x0 = 1 so that the same transform functions can be used.
+package classify
+
+import (
+	"fmt"
+	"math"
+)
+
+// Classifier predicts the label of a point.
+// The point has the form of the training points: x0 = 1 first and transformed.
+type Classifier interface {
+	Predict(x []float64) float64
+}
+
+// Data is the training data of a classifier.
+type Data struct {
+	Xn                [][]float64 // training points, x0 = 1 first.
+	Yn                []float64   // labels of the training points, -1 or +1.
+	Wn                []float64   // weights of the training points, nil when they all weigh 1.
+	TrainingPoints    int         // number of training points.
+	VectorSize        int         // size of a training point.
+	HasTransform      bool
+	TransformFunction func([]float64) ([]float64, error)
+	Folds             int // number of folds of the cross validation error.
+
+	errors errorCache
+}
+
+// errorCache memoizes the errors of a classifier, as rankings ask for
+// them many times and they are costly to compute.
+type errorCache struct {
+	ein, ecv       float64
+	hasEin, hasEcv bool
+}
+
+// ResetErrors forgets the memoized errors. Classifiers call it when they learn.
+func (d *Data) ResetErrors() {
+	d.errors = errorCache{}
+}
+
+// CachedEin returns the in sample error computed by ein, once per learning.
+func (d *Data) CachedEin(ein func() float64) float64 {
+	if !d.errors.hasEin {
+		d.errors.ein, d.errors.hasEin = ein(), true
+	}
+	return d.errors.ein
+}
+
+// CachedEcv returns the cross validation error computed by ecv, once per learning.
+func (d *Data) CachedEcv(ecv func() float64) float64 {
+	if !d.errors.hasEcv {
+		d.errors.ecv, d.errors.hasEcv = ecv(), true
+	}
+	return d.errors.ecv
+}
+
+// InitializeFromData sets the training points and labels of the data passed in.
+// The last column of each row is the label.
+func (d *Data) InitializeFromData(data [][]float64) error {
+	d.Xn = make([][]float64, len(data))
+	d.Yn = make([]float64, len(data))
+	for i, row := range data {
+		if len(row) == 0 {
+			return fmt.Errorf("empty row %v", i)
+		}
+		d.Xn[i] = append([]float64{1}, row[:len(row)-1]...)
+		d.Yn[i] = row[len(row)-1]
+	}
+	d.Wn = nil
+	d.TrainingPoints = len(d.Xn)
+	d.ResetErrors()
+	d.VectorSize = 0
+	if len(d.Xn) > 0 {
+		d.VectorSize = len(d.Xn[0])
+	}
+	return nil
+}
+
+// SetWeights sets the weights of the training points, in the order of the
+// rows of InitializeFromData. The weights are used by learning only, the
+// errors of a classifier still count each misclassified point once.
+func (d *Data) SetWeights(w []float64) error {
+	if len(w) != len(d.Xn) {
+		return fmt.Errorf("%v weights for %v training points", len(w), len(d.Xn))
+	}
+	var total float64
+	for i, v := range w {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid weight %v of training point %v", v, i)
+		}
+		total += v
+	}
+	if total == 0 {
+		return fmt.Errorf("the training points weigh 0")
+	}
+	d.Wn = w
+	d.ResetErrors()
+	return nil
+}
+
+// Weight returns the weight of the training point i.
+func (d *Data) Weight(i int) float64 {
+	if d.Wn == nil {
+		return 1
+	}
+	return d.Wn[i]
+}
+
+// ApplyTransformation transforms every training point with the transform function.
+func (d *Data) ApplyTransformation() error {
+	if d.TransformFunction == nil {
+		return fmt.Errorf("no transform function")
+	}
+	d.HasTransform = true
+	for i := range d.Xn {
+		x, err := d.TransformFunction(d.Xn[i])
+		if err != nil {
+			return err
+		}
+		d.Xn[i] = x
+	}
+	if len(d.Xn) > 0 {
+		d.VectorSize = len(d.Xn[0])
+	}
+	return nil
+}
+
+// Points returns the points passed in, without labels, in the form of the
+// training points: with x0 = 1 first and transformed if the data is.
+func (d *Data) Points(x [][]float64) ([][]float64, error) {
+	points := make([][]float64, len(x))
+	for i, row := range x {
+		p := append([]float64{1}, row...)
+		if d.HasTransform {
+			var err error
+			if p, err = d.TransformFunction(p); err != nil {
+				return nil, err
+			}
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
+// Subset returns the training data made of the rows passed in.
+// The rows are already transformed so the subset has no transform function.
+func (d *Data) Subset(rows []int) Data {
+	s := Data{Folds: d.Folds}
+	for _, i := range rows {
+		s.Xn = append(s.Xn, d.Xn[i])
+		s.Yn = append(s.Yn, d.Yn[i])
+		if d.Wn != nil {
+			s.Wn = append(s.Wn, d.Wn[i])
+		}
+	}
+	s.TrainingPoints = len(s.Xn)
+	s.VectorSize = d.VectorSize
+	return s
+}
+
+// Predictions returns the predictions of the classifier for the points
+// passed in, without labels.
+func Predictions(c Classifier, d *Data, x [][]float64) ([]float64, error) {
+	return Scores(c.Predict, d, x)
+}
+
+// Scores returns the score of each of the points passed in, without labels,
+// by the score function of a classifier, like Predict or a probability.
+func Scores(score func(x []float64) float64, d *Data, x [][]float64) ([]float64, error) {
+	points, err := d.Points(x)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(points))
+	for i, p := range points {
+		scores[i] = score(p)
+	}
+	return scores, nil
+}
+
+// Logistic returns the probability 1 / (1 + e^-s) of the log odds s.
+func Logistic(s float64) float64 {
+	return 1 / (1 + math.Exp(-s))
+}
+
+// Ein returns the fraction of training points misclassified by the classifier.
+func Ein(c Classifier, d *Data) float64 {
+	if len(d.Xn) == 0 {
+		return 1
+	}
+	var wrong int
+	for i, x := range d.Xn {
+		if c.Predict(x) != d.Yn[i] {
+			wrong++
+		}
+	}
+	return float64(wrong) / float64(len(d.Xn))
+}
+
+// Folds returns the row indexes of each of the k folds of a data set
+// of n rows. Row i belongs to fold i % k.
+// The cross validation of every model of the titanic program uses these
+// folds, so that their errors are comparable.
+func Folds(n, k int) (folds [][]int) {
+	if k > n {
+		k = n
+	}
+	if k < 2 {
+		k = 2
+	}
+	folds = make([][]int, k)
+	for i := 0; i < n; i++ {
+		folds[i%k] = append(folds[i%k], i)
+	}
+	return
+}
+
+// CrossValidation returns the fraction of training points misclassified
+// when the points of each fold are predicted by a classifier trained by
+// train on the other folds.
+// It returns 1 if a classifier cannot be trained.
+func CrossValidation(d *Data, train func(d Data) (Classifier, error)) float64 {
+	if len(d.Xn) < 2 {
+		return 1
+	}
+	var wrong int
+	for _, fold := range Folds(len(d.Xn), d.Folds) {
+		in := make(map[int]bool)
+		for _, i := range fold {
+			in[i] = true
+		}
+		var rows []int
+		for i := range d.Xn {
+			if !in[i] {
+				rows = append(rows, i)
+			}
+		}
+		c, err := train(d.Subset(rows))
+		if err != nil {
+			return 1
+		}
+		for _, i := range fold {
+			if c.Predict(d.Xn[i]) != d.Yn[i] {
+				wrong++
+			}
+		}
+	}
+	return float64(wrong) / float64(len(d.Xn))
+}
+
+// Sign returns +1 if v is positive or zero and -1 otherwise.
+func Sign(v float64) float64 {
+	if v >= 0 {
+		return 1
+	}
+	return -1
+}
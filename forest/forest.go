@@ -0,0 +1,227 @@
+// Package forest implements a random forest binary classifier.
+package forest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/tree"
+)
+
+// Forest is a random forest: decision trees grown on bootstrap samples of
+// the training points, trying a random subset of the coordinates at each
+// split, that vote the label of a point. The trees are grown with the
+// weights of their training points, if any.
+type Forest struct {
+	classify.Data
+	Trees     int    // number of trees.
+	Features  int    // number of coordinates tried at each split, 0 is the square root of their number.
+	Bootstrap bool   // grow each tree on a bootstrap sample instead of every training point.
+	MaxDepth  int    // maximum depth of the trees.
+	MinLeaf   int    // minimum number of training points in a leaf.
+	Criterion string // tree.Gini or tree.Entropy.
+	Seed      int64  // seed of the seeds of the trees.
+
+	Seeds   []int64      // seed of each tree, derived from Seed.
+	Members []*tree.Tree // learned trees, without their training points.
+}
+
+// NewForest returns a forest of 100 trees grown on bootstrap samples, of
+// depth 10 with at least one point per leaf, the Gini criterion, seed 1 and
+// 10 folds of cross validation when there is no bootstrap.
+func NewForest() *Forest {
+	return &Forest{
+		Data:      classify.Data{Folds: 10},
+		Trees:     100,
+		Bootstrap: true,
+		MaxDepth:  10,
+		MinLeaf:   1,
+		Criterion: tree.Gini,
+		Seed:      1,
+	}
+}
+
+// features returns the number of coordinates tried at each split.
+func (f *Forest) features() int {
+	if f.Features > 0 {
+		return f.Features
+	}
+	// x0 = 1 is never a useful split so it is not counted.
+	n := int(math.Sqrt(float64(f.VectorSize - 1)))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Learn grows the trees of the forest in parallel.
+// The result only depends on Seed, not on the order the trees are grown in.
+func (f *Forest) Learn() error {
+	f.ResetErrors()
+	f.Members = nil
+	if f.Trees < 1 {
+		return fmt.Errorf("invalid number of trees %v", f.Trees)
+	}
+	if len(f.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+	r := rand.New(rand.NewSource(f.Seed))
+	f.Seeds = make([]int64, f.Trees)
+	for i := range f.Seeds {
+		f.Seeds[i] = r.Int63()
+	}
+
+	members := make([]*tree.Tree, f.Trees)
+	errs := make([]error, f.Trees)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				members[i], errs[i] = f.grow(i)
+			}
+		}()
+	}
+	for i := range members {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	f.Members = members
+	return nil
+}
+
+// grow returns the i-th tree of the forest.
+func (f *Forest) grow(i int) (*tree.Tree, error) {
+	t := tree.NewTree()
+	t.Data = f.Subset(f.sample(i))
+	t.MaxDepth = f.MaxDepth
+	t.MinLeaf = f.MinLeaf
+	t.Criterion = f.Criterion
+	t.Features = f.features()
+	t.Seed = f.Seeds[i]
+	if err := t.Learn(); err != nil {
+		return nil, err
+	}
+	// the tree only needs its nodes to predict.
+	t.Data = classify.Data{}
+	return t, nil
+}
+
+// sample returns the rows of the training points of the i-th tree:
+// a bootstrap sample drawn with the seed of the tree or every row.
+func (f *Forest) sample(i int) []int {
+	n := len(f.Xn)
+	rows := make([]int, n)
+	if !f.Bootstrap {
+		for j := range rows {
+			rows[j] = j
+		}
+		return rows
+	}
+	r := rand.New(rand.NewSource(f.Seeds[i]))
+	for j := range rows {
+		rows[j] = r.Intn(n)
+	}
+	return rows
+}
+
+// vote returns the sum of the labels predicted for x by the trees
+// for which use returns true.
+func (f *Forest) vote(x []float64, use func(i int) bool) (vote float64, voters int) {
+	for i, t := range f.Members {
+		if use(i) {
+			vote += t.Predict(x)
+			voters++
+		}
+	}
+	return
+}
+
+// Predict returns the label voted by the trees of the forest.
+func (f *Forest) Predict(x []float64) float64 {
+	vote, _ := f.vote(x, func(int) bool { return true })
+	return classify.Sign(vote)
+}
+
+// Probability returns the mean of the probabilities of the label +1 of x
+// given by the trees.
+func (f *Forest) Probability(x []float64) float64 {
+	if len(f.Members) == 0 {
+		return 0.5
+	}
+	var p float64
+	for _, t := range f.Members {
+		p += t.Probability(x)
+	}
+	return p / float64(len(f.Members))
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (f *Forest) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(f, &f.Data, x)
+}
+
+// Ein returns the in sample error.
+func (f *Forest) Ein() float64 {
+	return f.CachedEin(func() float64 { return classify.Ein(f, &f.Data) })
+}
+
+// Ecv returns the out of bag error: the error of the vote of the trees
+// that were not grown on each training point. Points that are in the
+// sample of every tree are not counted.
+// Without bootstrap samples it is the cross validation error.
+func (f *Forest) Ecv() float64 {
+	return f.CachedEcv(func() float64 {
+		if !f.Bootstrap {
+			return classify.CrossValidation(&f.Data, func(d classify.Data) (classify.Classifier, error) {
+				c := *f
+				c.Data = d
+				return &c, c.Learn()
+			})
+		}
+		return f.oob()
+	})
+}
+
+// oob returns the out of bag error of the forest.
+func (f *Forest) oob() float64 {
+	if len(f.Members) == 0 || len(f.Seeds) != len(f.Members) {
+		return 1
+	}
+	n := len(f.Xn)
+	inBag := make([][]bool, len(f.Members))
+	for i := range f.Members {
+		inBag[i] = make([]bool, n)
+		for _, j := range f.sample(i) {
+			inBag[i][j] = true
+		}
+	}
+	var wrong, counted int
+	for j, x := range f.Xn {
+		vote, voters := f.vote(x, func(i int) bool { return !inBag[i][j] })
+		if voters == 0 {
+			continue
+		}
+		counted++
+		if classify.Sign(vote) != f.Yn[j] {
+			wrong++
+		}
+	}
+	if counted == 0 {
+		return 1
+	}
+	return float64(wrong) / float64(counted)
+}
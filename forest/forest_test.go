@@ -0,0 +1,65 @@
+package forest
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns noisy points labeled +1 when both coordinates are positive.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		a, b := r.Float64()*4-2, r.Float64()*4-2
+		y := -1.0
+		if a > 0 && b > 0 {
+			y = 1
+		}
+		data = append(data, []float64{a, b, r.Float64(), y})
+	}
+	return
+}
+
+func learn(t *testing.T, seed int64) *Forest {
+	f := NewForest()
+	f.Trees = 30
+	f.Seed = seed
+	if err := f.InitializeFromData(points()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestForestLearn(t *testing.T) {
+	f := learn(t, 1)
+	if len(f.Members) != 30 || len(f.Seeds) != 30 {
+		t.Fatalf("got %v trees and %v seeds, want 30", len(f.Members), len(f.Seeds))
+	}
+	if ein := f.Ein(); ein > 0.05 {
+		t.Errorf("Ein = %v, want at most 0.05", ein)
+	}
+	if ecv := f.Ecv(); ecv > 0.15 {
+		t.Errorf("out of bag error = %v, want at most 0.15", ecv)
+	}
+	predictions, err := f.Predictions([][]float64{{1.5, 1.5, 0.5}, {-1.5, 1.5, 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if predictions[0] != 1 || predictions[1] != -1 {
+		t.Errorf("wrong predictions %v", predictions)
+	}
+}
+
+func TestForestSeed(t *testing.T) {
+	a, b := learn(t, 3), learn(t, 3)
+	for i := range a.Seeds {
+		if a.Seeds[i] != b.Seeds[i] || a.Members[i].Depth() != b.Members[i].Depth() {
+			t.Fatalf("tree %v differs between two forests of the same seed", i)
+		}
+	}
+	if a.Ecv() != b.Ecv() {
+		t.Errorf("out of bag errors %v and %v differ for the same seed", a.Ecv(), b.Ecv())
+	}
+}
@@ -23,7 +23,10 @@ func Combinations(iterable []int, r int) (results [][]int) {
 		result[i] = pool[el]
 	}
 
-	results = append(results, result)
+	// result is reused for the next combinations, so the first one is a copy.
+	first := make([]int, r)
+	copy(first, result)
+	results = append(results, first)
 	for {
 		i := r - 1
 		for ; i >= 0 && indices[i] == i+n-r; i-- {
@@ -46,3 +49,13 @@ func Combinations(iterable []int, r int) (results [][]int) {
 		results = append(results, newRes)
 	}
 }
+
+// CombinationsRange returns an 2D array with all the combinations of the iterable
+// array passed as argument for every size from 'from' to 'to' included.
+// Combinations are ordered by size.
+func CombinationsRange(iterable []int, from, to int) (results [][]int) {
+	for r := from; r <= to; r++ {
+		results = append(results, Combinations(iterable, r)...)
+	}
+	return
+}
@@ -0,0 +1,41 @@
+package itertools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCombinations(t *testing.T) {
+	tests := []struct {
+		iterable []int
+		r        int
+		want     [][]int
+	}{
+		{[]int{1, 2, 3}, 2, [][]int{{1, 2}, {1, 3}, {2, 3}}},
+		{[]int{1, 2, 3}, 3, [][]int{{1, 2, 3}}},
+		{[]int{1, 2, 3}, 4, [][]int{}},
+		{[]int{4, 5, 6, 7}, 1, [][]int{{4}, {5}, {6}, {7}}},
+	}
+	for _, tt := range tests {
+		if got := Combinations(tt.iterable, tt.r); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Combinations(%v, %v) = %v, want %v", tt.iterable, tt.r, got, tt.want)
+		}
+	}
+}
+
+// The first combination used to share its array with the next ones, so it
+// ended up holding the last combination.
+func TestCombinationsFirstIsNotOverwritten(t *testing.T) {
+	got := Combinations([]int{1, 2, 3, 4}, 2)
+	if !reflect.DeepEqual(got[0], []int{1, 2}) {
+		t.Errorf("first combination = %v, want [1 2]", got[0])
+	}
+}
+
+func TestCombinationsRange(t *testing.T) {
+	got := CombinationsRange([]int{1, 2, 3}, 1, 2)
+	want := [][]int{{1}, {2}, {3}, {1, 2}, {1, 3}, {2, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CombinationsRange = %v, want %v", got, want)
+	}
+}
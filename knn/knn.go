@@ -0,0 +1,170 @@
+// Package knn implements a k-nearest-neighbours binary classifier.
+package knn
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Distances between two points.
+const (
+	Euclidean = "euclid"
+	Manhattan = "manhattan"
+)
+
+// Weightings of the votes of the neighbours.
+const (
+	Uniform  = "uniform"  // every neighbour has one vote.
+	Distance = "distance" // the vote of a neighbour is the inverse of its distance.
+)
+
+// KNN is a k-nearest-neighbours classifier.
+// It predicts the label of a point by a vote of the K training points
+// closest to it.
+type KNN struct {
+	classify.Data
+	K         int    // number of neighbours.
+	Distance  string // Euclidean or Manhattan.
+	Weighting string // Uniform or Distance.
+}
+
+// NewKNN returns a 5-nearest-neighbours classifier with Euclidean distance,
+// uniform weighting and 10 folds of cross validation.
+func NewKNN() *KNN {
+	return &KNN{
+		Data:      classify.Data{Folds: 10},
+		K:         5,
+		Distance:  Euclidean,
+		Weighting: Uniform,
+	}
+}
+
+// Learn checks the parameters of the classifier.
+// A kNN has nothing to learn, the training points are its model.
+func (k *KNN) Learn() error {
+	k.ResetErrors()
+	if k.K < 1 {
+		return fmt.Errorf("invalid number of neighbours %v", k.K)
+	}
+	if k.Distance != Euclidean && k.Distance != Manhattan {
+		return fmt.Errorf("unknown distance %v", k.Distance)
+	}
+	if k.Weighting != Uniform && k.Weighting != Distance {
+		return fmt.Errorf("unknown weighting %v", k.Weighting)
+	}
+	if len(k.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+	return nil
+}
+
+// distance returns the distance between a and b.
+func (k *KNN) distance(a, b []float64) (d float64) {
+	if k.Distance == Manhattan {
+		for i := range a {
+			d += math.Abs(a[i] - b[i])
+		}
+		return
+	}
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(d)
+}
+
+// neighbour is a training point and its distance to the point to predict.
+type neighbour struct {
+	index    int
+	distance float64
+}
+
+// Predict returns the label voted by the K nearest training points of x.
+// Ties are broken by the label of the nearest training point.
+func (k *KNN) Predict(x []float64) float64 {
+	nearest := k.nearest(x)
+	if len(nearest) == 0 {
+		return 1
+	}
+	var vote float64
+	for _, nb := range nearest {
+		vote += k.weight(nb) * k.Yn[nb.index]
+	}
+	if vote == 0 {
+		return k.Yn[nearest[0].index]
+	}
+	return classify.Sign(vote)
+}
+
+// Probability returns the weighted fraction of the K nearest neighbours of
+// x labeled +1.
+func (k *KNN) Probability(x []float64) float64 {
+	var positive, total float64
+	for _, nb := range k.nearest(x) {
+		w := k.weight(nb)
+		total += w
+		if k.Yn[nb.index] > 0 {
+			positive += w
+		}
+	}
+	if total == 0 {
+		return 0.5
+	}
+	return positive / total
+}
+
+// weight returns the weight of the vote of the neighbour passed in,
+// scaled by the weight of its training point.
+func (k *KNN) weight(nb neighbour) float64 {
+	if k.Weighting == Distance {
+		// a training point equal to x outweighs every other neighbour.
+		return k.Weight(nb.index) / math.Max(nb.distance, 1e-9)
+	}
+	return k.Weight(nb.index)
+}
+
+// nearest returns the K training points closest to x, nearest first.
+// Among points at the same distance the first training points are kept.
+func (k *KNN) nearest(x []float64) []neighbour {
+	nearest := make([]neighbour, 0, k.K+1)
+	for i, xn := range k.Xn {
+		d := k.distance(x, xn)
+		if len(nearest) == k.K && d >= nearest[len(nearest)-1].distance {
+			continue
+		}
+		j := len(nearest)
+		for j > 0 && nearest[j-1].distance > d {
+			j--
+		}
+		nearest = append(nearest, neighbour{})
+		copy(nearest[j+1:], nearest[j:])
+		nearest[j] = neighbour{i, d}
+		if len(nearest) > k.K {
+			nearest = nearest[:k.K]
+		}
+	}
+	return nearest
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (k *KNN) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(k, &k.Data, x)
+}
+
+// Ein returns the in sample error.
+// Each training point is among its own neighbours.
+func (k *KNN) Ein() float64 {
+	return k.CachedEin(func() float64 { return classify.Ein(k, &k.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (k *KNN) Ecv() float64 {
+	return k.CachedEcv(func() float64 {
+		return classify.CrossValidation(&k.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *k
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
@@ -0,0 +1,54 @@
+package knn
+
+import "testing"
+
+// points returns two clusters of points, labeled -1 around (0, 0) and
+// +1 around (10, 10).
+func points() (data [][]float64) {
+	for i := 0; i < 10; i++ {
+		d := float64(i%3) * 0.1
+		data = append(data, []float64{d, d, -1}, []float64{10 + d, 10 - d, 1})
+	}
+	return
+}
+
+func TestKNNPredictions(t *testing.T) {
+	for _, distance := range []string{Euclidean, Manhattan} {
+		for _, weighting := range []string{Uniform, Distance} {
+			k := NewKNN()
+			k.Distance = distance
+			k.Weighting = weighting
+			if err := k.InitializeFromData(points()); err != nil {
+				t.Fatal(err)
+			}
+			if err := k.Learn(); err != nil {
+				t.Fatal(err)
+			}
+			predictions, err := k.Predictions([][]float64{{1, 1}, {9, 9}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if predictions[0] != -1 || predictions[1] != 1 {
+				t.Errorf("%v %v: wrong predictions %v", distance, weighting, predictions)
+			}
+			if ein := k.Ein(); ein != 0 {
+				t.Errorf("%v %v: Ein = %v, want 0", distance, weighting, ein)
+			}
+			if ecv := k.Ecv(); ecv != 0 {
+				t.Errorf("%v %v: Ecv = %v, want 0", distance, weighting, ecv)
+			}
+		}
+	}
+}
+
+func TestKNNLearnErrors(t *testing.T) {
+	k := NewKNN()
+	if err := k.Learn(); err == nil {
+		t.Error("expected an error without training points")
+	}
+	k.InitializeFromData(points())
+	k.K = 0
+	if err := k.Learn(); err == nil {
+		t.Error("expected an error with k = 0")
+	}
+}
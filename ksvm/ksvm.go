@@ -0,0 +1,278 @@
+// Package ksvm implements a kernel support vector machine trained with
+// sequential minimal optimization.
+package ksvm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Kernels of the support vector machine.
+const (
+	RBF  = "rbf"  // exp(-gamma |x - z|^2)
+	Poly = "poly" // (gamma x . z + 1)^degree
+)
+
+// KernelSVM is a soft margin support vector machine with a kernel.
+// Coordinates are standardized with the mean and standard deviation of the
+// training points before the kernel is applied. x0 = 1 is left out as the
+// bias is learned apart.
+type KernelSVM struct {
+	classify.Data
+	Kernel        string  // RBF or Poly.
+	C             float64 // penalty of the margin violations, scaled by the weight of each training point.
+	Gamma         float64 // scale of the kernel, 0 is 1 over the number of coordinates.
+	Degree        int     // degree of the polynomial kernel.
+	Tolerance     float64 // stopping tolerance on the violation of the optimality conditions.
+	MaxIterations int     // maximum number of optimization steps.
+
+	Mean    []float64   // mean of each coordinate of the training points.
+	Scale   []float64   // standard deviation of each coordinate, 1 if it is constant.
+	Support [][]float64 // standardized support vectors.
+	Coef    []float64   // alpha times label of each support vector.
+	Rho     float64     // the decision function is sum(Coef K(Support, x)) - Rho.
+}
+
+// NewKernelSVM returns an RBF kernel SVM with C = 1, gamma 1 over the
+// number of coordinates, degree 3 for the polynomial kernel, tolerance
+// 0.001, at most 10000 iterations and 10 folds of cross validation.
+func NewKernelSVM() *KernelSVM {
+	return &KernelSVM{
+		Data:          classify.Data{Folds: 10},
+		Kernel:        RBF,
+		C:             1,
+		Degree:        3,
+		Tolerance:     1e-3,
+		MaxIterations: 10000,
+	}
+}
+
+// gamma returns the scale of the kernel.
+func (s *KernelSVM) gamma() float64 {
+	if s.Gamma > 0 {
+		return s.Gamma
+	}
+	return 1 / math.Max(float64(len(s.Mean)-1), 1)
+}
+
+// kernel returns the kernel of the standardized points a and b.
+func (s *KernelSVM) kernel(a, b []float64) float64 {
+	var k float64
+	if s.Kernel == Poly {
+		for j := 1; j < len(a); j++ {
+			k += a[j] * b[j]
+		}
+		return math.Pow(s.gamma()*k+1, float64(s.Degree))
+	}
+	for j := 1; j < len(a); j++ {
+		k += (a[j] - b[j]) * (a[j] - b[j])
+	}
+	return math.Exp(-s.gamma() * k)
+}
+
+// standardize returns x with standardized coordinates.
+func (s *KernelSVM) standardize(x []float64) []float64 {
+	z := make([]float64, len(x))
+	for j := range x {
+		if j < len(s.Mean) {
+			z[j] = (x[j] - s.Mean[j]) / s.Scale[j]
+		}
+	}
+	return z
+}
+
+// Learn solves the dual problem of the SVM by sequential minimal
+// optimization, updating at each step the pair of multipliers that
+// violates the most the optimality conditions, as in LIBSVM.
+func (s *KernelSVM) Learn() error {
+	s.ResetErrors()
+	s.Support, s.Coef = nil, nil
+	if s.Kernel != RBF && s.Kernel != Poly {
+		return fmt.Errorf("unknown kernel %v", s.Kernel)
+	}
+	if s.C <= 0 || s.Gamma < 0 || s.Degree < 1 {
+		return fmt.Errorf("invalid parameters C %v gamma %v degree %v", s.C, s.Gamma, s.Degree)
+	}
+	if len(s.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+
+	n := len(s.Xn)
+	s.Mean = make([]float64, s.VectorSize)
+	s.Scale = make([]float64, s.VectorSize)
+	for j := range s.Mean {
+		for _, x := range s.Xn {
+			s.Mean[j] += x[j]
+		}
+		s.Mean[j] /= float64(n)
+		for _, x := range s.Xn {
+			s.Scale[j] += (x[j] - s.Mean[j]) * (x[j] - s.Mean[j])
+		}
+		if s.Scale[j] = math.Sqrt(s.Scale[j] / float64(n)); s.Scale[j] == 0 {
+			s.Scale[j] = 1
+		}
+	}
+	// identical points with the same label share a multiplier bounded by
+	// C times their weight, which solves the same problem faster.
+	var z [][]float64
+	var y, bound []float64
+	index := make(map[string]int)
+	for i, x := range s.Xn {
+		key := fmt.Sprint(x, s.Yn[i])
+		if t, ok := index[key]; ok {
+			bound[t] += s.C * s.Weight(i)
+			continue
+		}
+		index[key] = len(z)
+		z = append(z, s.standardize(x))
+		y = append(y, s.Yn[i])
+		bound = append(bound, s.C*s.Weight(i))
+	}
+	n = len(z)
+	k := make([][]float64, n)
+	for i := range k {
+		k[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			k[i][j] = s.kernel(z[i], z[j])
+			k[j][i] = k[i][j]
+		}
+	}
+
+	alpha := make([]float64, n)
+	grad := make([]float64, n) // gradient of the dual objective.
+	for i := range grad {
+		grad[i] = -1
+	}
+	up := func(t int) bool { return (y[t] == 1 && alpha[t] < bound[t]) || (y[t] == -1 && alpha[t] > 0) }
+	low := func(t int) bool { return (y[t] == 1 && alpha[t] > 0) || (y[t] == -1 && alpha[t] < bound[t]) }
+
+	var m, M float64
+	for iteration := 0; iteration < s.MaxIterations; iteration++ {
+		i, j := -1, -1
+		m, M = math.Inf(-1), math.Inf(1)
+		for t := range alpha {
+			v := -y[t] * grad[t]
+			if up(t) && v > m {
+				i, m = t, v
+			}
+			if low(t) && v < M {
+				j, M = t, v
+			}
+		}
+		if i < 0 || j < 0 || m-M < s.Tolerance {
+			break
+		}
+		ai, aj := alpha[i], alpha[j]
+		ci, cj := bound[i], bound[j]
+		qij := y[i] * y[j] * k[i][j]
+		if y[i] != y[j] {
+			quad := math.Max(k[i][i]+k[j][j]+2*qij, 1e-12)
+			delta := (-grad[i] - grad[j]) / quad
+			diff := alpha[i] - alpha[j]
+			alpha[i] += delta
+			alpha[j] += delta
+			if diff > 0 {
+				if alpha[j] < 0 {
+					alpha[j], alpha[i] = 0, diff
+				}
+			} else if alpha[i] < 0 {
+				alpha[i], alpha[j] = 0, -diff
+			}
+			if diff > ci-cj {
+				if alpha[i] > ci {
+					alpha[i], alpha[j] = ci, ci-diff
+				}
+			} else if alpha[j] > cj {
+				alpha[j], alpha[i] = cj, cj+diff
+			}
+		} else {
+			quad := math.Max(k[i][i]+k[j][j]-2*qij, 1e-12)
+			delta := (grad[i] - grad[j]) / quad
+			sum := alpha[i] + alpha[j]
+			alpha[i] -= delta
+			alpha[j] += delta
+			if sum > ci {
+				if alpha[i] > ci {
+					alpha[i], alpha[j] = ci, sum-ci
+				}
+			} else if alpha[j] < 0 {
+				alpha[j], alpha[i] = 0, sum
+			}
+			if sum > cj {
+				if alpha[j] > cj {
+					alpha[j], alpha[i] = cj, sum-cj
+				}
+			} else if alpha[i] < 0 {
+				alpha[i], alpha[j] = 0, sum
+			}
+		}
+		// k is symmetric, rows are faster to read than columns.
+		di, dj := y[i]*(alpha[i]-ai), y[j]*(alpha[j]-aj)
+		ki, kj := k[i], k[j]
+		for t := range grad {
+			grad[t] += y[t] * (ki[t]*di + kj[t]*dj)
+		}
+	}
+
+	// the bias is the mean over the free multipliers or the middle of the
+	// last violation when there are none.
+	var sum float64
+	var free int
+	for t := range alpha {
+		if alpha[t] > 0 && alpha[t] < bound[t] {
+			sum += y[t] * grad[t]
+			free++
+		}
+	}
+	if free > 0 {
+		s.Rho = sum / float64(free)
+	} else {
+		s.Rho = -(m + M) / 2
+	}
+	for t := range alpha {
+		if alpha[t] > 0 {
+			s.Support = append(s.Support, z[t])
+			s.Coef = append(s.Coef, alpha[t]*y[t])
+		}
+	}
+	return nil
+}
+
+// Decision returns the value of the decision function of x, a point in
+// the form of the training points, not standardized.
+func (s *KernelSVM) Decision(x []float64) float64 {
+	z := s.standardize(x)
+	d := -s.Rho
+	for t, sv := range s.Support {
+		d += s.Coef[t] * s.kernel(sv, z)
+	}
+	return d
+}
+
+// Predict returns the sign of the decision function of x.
+func (s *KernelSVM) Predict(x []float64) float64 {
+	return classify.Sign(s.Decision(x))
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (s *KernelSVM) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(s, &s.Data, x)
+}
+
+// Ein returns the in sample error.
+func (s *KernelSVM) Ein() float64 {
+	return s.CachedEin(func() float64 { return classify.Ein(s, &s.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (s *KernelSVM) Ecv() float64 {
+	return s.CachedEcv(func() float64 {
+		return classify.CrossValidation(&s.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *s
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package ksvm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns points labeled +1 inside the circle of radius 1 and -1
+// outside of it, which no line separates.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for len(data) < 200 {
+		a, b := r.Float64()*4-2, r.Float64()*4-2
+		d := a*a + b*b
+		if d > 0.8 && d < 1.2 {
+			continue
+		}
+		y := -1.0
+		if d < 1 {
+			y = 1
+		}
+		data = append(data, []float64{a, b, y})
+	}
+	return
+}
+
+func TestKernelSVM(t *testing.T) {
+	for _, kernel := range []string{RBF, Poly} {
+		s := NewKernelSVM()
+		s.Kernel = kernel
+		s.Degree = 2
+		s.C = 10
+		if err := s.InitializeFromData(points()); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Learn(); err != nil {
+			t.Fatal(err)
+		}
+		if ein := s.Ein(); ein > 0.03 {
+			t.Errorf("%v: Ein = %v, want at most 0.03", kernel, ein)
+		}
+		if ecv := s.Ecv(); ecv > 0.08 {
+			t.Errorf("%v: Ecv = %v, want at most 0.08", kernel, ecv)
+		}
+		predictions, err := s.Predictions([][]float64{{0, 0}, {1.8, -1.8}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if predictions[0] != 1 || predictions[1] != -1 {
+			t.Errorf("%v: wrong predictions %v", kernel, predictions)
+		}
+		if len(s.Support) == 0 || len(s.Support) == len(s.Xn) {
+			t.Errorf("%v: %v support vectors out of %v points", kernel, len(s.Support), len(s.Xn))
+		}
+	}
+}
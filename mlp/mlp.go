@@ -0,0 +1,336 @@
+// Package mlp implements a small multilayer perceptron binary classifier.
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Activations of the hidden units.
+const (
+	ReLU    = "relu"
+	Tanh    = "tanh"
+	Sigmoid = "sigmoid"
+)
+
+// MLP is a feed-forward neural network with one or two hidden layers and
+// a sigmoid output unit, trained by mini-batch gradient descent on the
+// logistic loss with an L2 penalty. The loss of each training point is
+// scaled by its weight, if any.
+// Coordinates are standardized with the mean and standard deviation of the
+// training points. x0 = 1 is left out as every unit has its own bias.
+type MLP struct {
+	classify.Data
+	Hidden        []int   // number of units of each hidden layer.
+	Activation    string  // ReLU, Tanh or Sigmoid.
+	L2            float64 // penalty of the squared weights.
+	LearningRate  float64 // step of the gradient descent.
+	Epochs        int     // maximum number of passes over the training points.
+	BatchSize     int     // number of training points of each gradient step.
+	EarlyStopping int     // epochs without improvement of the validation loss before stopping, 0 disables it.
+	Seed          int64   // seed of the initial weights and of the order of the training points.
+
+	Mean    []float64     // mean of each coordinate of the training points.
+	Scale   []float64     // standard deviation of each coordinate, 1 if it is constant.
+	Weights [][][]float64 // weights of each layer: one row per unit, its bias last.
+}
+
+// NewMLP returns a network of one hidden layer of 8 ReLU units, an L2
+// penalty of 0.0001, learning rate 0.01, 100 epochs of batches of 32
+// points, no early stopping, seed 1 and 10 folds of cross validation.
+func NewMLP() *MLP {
+	return &MLP{
+		Data:         classify.Data{Folds: 10},
+		Hidden:       []int{8},
+		Activation:   ReLU,
+		L2:           0.0001,
+		LearningRate: 0.01,
+		Epochs:       100,
+		BatchSize:    32,
+		Seed:         1,
+	}
+}
+
+// Learn initializes the weights at random and trains them.
+// With early stopping the points of the first fold are held out for
+// validation and the weights of the epoch with the lowest validation loss
+// are kept.
+func (n *MLP) Learn() error {
+	n.ResetErrors()
+	n.Weights = nil
+	if len(n.Hidden) < 1 || len(n.Hidden) > 2 {
+		return fmt.Errorf("invalid number of hidden layers %v", len(n.Hidden))
+	}
+	for _, h := range n.Hidden {
+		if h < 1 {
+			return fmt.Errorf("invalid hidden layer width %v", h)
+		}
+	}
+	if n.Activation != ReLU && n.Activation != Tanh && n.Activation != Sigmoid {
+		return fmt.Errorf("unknown activation %v", n.Activation)
+	}
+	if n.L2 < 0 || n.LearningRate <= 0 || n.Epochs < 1 || n.BatchSize < 1 || n.EarlyStopping < 0 {
+		return fmt.Errorf("invalid training parameters l2 %v rate %v epochs %v batch %v stop %v",
+			n.L2, n.LearningRate, n.Epochs, n.BatchSize, n.EarlyStopping)
+	}
+	if len(n.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+
+	n.standardization()
+	z := make([][]float64, len(n.Xn))
+	for i, x := range n.Xn {
+		z[i] = n.standardize(x)
+	}
+
+	var train, validation []int
+	if n.EarlyStopping > 0 {
+		folds := classify.Folds(len(n.Xn), n.Folds)
+		validation = folds[0]
+		for _, fold := range folds[1:] {
+			train = append(train, fold...)
+		}
+	} else {
+		for i := range n.Xn {
+			train = append(train, i)
+		}
+	}
+
+	r := rand.New(rand.NewSource(n.Seed))
+	n.initialize(r, len(z[0]))
+	best, bestWeights, since := math.Inf(1), n.Weights, 0
+	for epoch := 0; epoch < n.Epochs; epoch++ {
+		r.Shuffle(len(train), func(a, b int) { train[a], train[b] = train[b], train[a] })
+		for start := 0; start < len(train); start += n.BatchSize {
+			end := start + n.BatchSize
+			if end > len(train) {
+				end = len(train)
+			}
+			n.step(z, train[start:end])
+		}
+		if n.EarlyStopping == 0 {
+			continue
+		}
+		if loss := n.loss(z, validation); loss < best {
+			best, bestWeights, since = loss, copyWeights(n.Weights), 0
+		} else if since++; since >= n.EarlyStopping {
+			break
+		}
+	}
+	if n.EarlyStopping > 0 {
+		n.Weights = bestWeights
+	}
+	return nil
+}
+
+// standardization sets the mean and the scale of each coordinate.
+func (n *MLP) standardization() {
+	n.Mean = make([]float64, n.VectorSize)
+	n.Scale = make([]float64, n.VectorSize)
+	for j := range n.Mean {
+		for _, x := range n.Xn {
+			n.Mean[j] += x[j]
+		}
+		n.Mean[j] /= float64(len(n.Xn))
+		for _, x := range n.Xn {
+			n.Scale[j] += (x[j] - n.Mean[j]) * (x[j] - n.Mean[j])
+		}
+		if n.Scale[j] = math.Sqrt(n.Scale[j] / float64(len(n.Xn))); n.Scale[j] == 0 {
+			n.Scale[j] = 1
+		}
+	}
+}
+
+// standardize returns the standardized coordinates of x, without x0.
+func (n *MLP) standardize(x []float64) []float64 {
+	z := make([]float64, 0, len(x))
+	for j := 1; j < len(x) && j < len(n.Mean); j++ {
+		z = append(z, (x[j]-n.Mean[j])/n.Scale[j])
+	}
+	return z
+}
+
+// initialize sets random weights scaled by the number of inputs of each
+// unit and zero biases.
+func (n *MLP) initialize(r *rand.Rand, inputs int) {
+	sizes := append(append([]int{inputs}, n.Hidden...), 1)
+	n.Weights = make([][][]float64, len(sizes)-1)
+	for l := range n.Weights {
+		in, out := sizes[l], sizes[l+1]
+		bound := math.Sqrt(6 / float64(in+out))
+		n.Weights[l] = make([][]float64, out)
+		for u := range n.Weights[l] {
+			n.Weights[l][u] = make([]float64, in+1)
+			for k := 0; k < in; k++ {
+				n.Weights[l][u][k] = (2*r.Float64() - 1) * bound
+			}
+		}
+	}
+}
+
+func copyWeights(w [][][]float64) [][][]float64 {
+	c := make([][][]float64, len(w))
+	for l := range w {
+		c[l] = make([][]float64, len(w[l]))
+		for u := range w[l] {
+			c[l][u] = append([]float64(nil), w[l][u]...)
+		}
+	}
+	return c
+}
+
+// activate returns the activation of a hidden unit and its derivative.
+func (n *MLP) activate(s float64) (a, da float64) {
+	switch n.Activation {
+	case Tanh:
+		a = math.Tanh(s)
+		return a, 1 - a*a
+	case Sigmoid:
+		a = sigmoid(s)
+		return a, a * (1 - a)
+	}
+	if s > 0 {
+		return s, 1
+	}
+	return 0, 0
+}
+
+// forward returns the outputs of each layer for the standardized point z,
+// z first, and the derivatives of the activations of the hidden layers.
+// The output layer holds the log odds of the label +1.
+func (n *MLP) forward(z []float64) (outputs, derivatives [][]float64) {
+	outputs = [][]float64{z}
+	for l, layer := range n.Weights {
+		in := outputs[l]
+		out := make([]float64, len(layer))
+		d := make([]float64, len(layer))
+		for u, w := range layer {
+			s := w[len(in)]
+			for k, v := range in {
+				s += w[k] * v
+			}
+			if l == len(n.Weights)-1 {
+				out[u] = s
+				continue
+			}
+			out[u], d[u] = n.activate(s)
+		}
+		outputs = append(outputs, out)
+		derivatives = append(derivatives, d)
+	}
+	return
+}
+
+// step does a gradient step on the training points of rows.
+func (n *MLP) step(z [][]float64, rows []int) {
+	grads := make([][][]float64, len(n.Weights))
+	for l := range n.Weights {
+		grads[l] = make([][]float64, len(n.Weights[l]))
+		for u := range n.Weights[l] {
+			grads[l][u] = make([]float64, len(n.Weights[l][u]))
+		}
+	}
+	for _, i := range rows {
+		outputs, derivatives := n.forward(z[i])
+		score := outputs[len(outputs)-1][0]
+		// gradient of the weighted logistic loss with respect to the log odds.
+		delta := []float64{n.Weight(i) * (sigmoid(score) - (n.Yn[i]+1)/2)}
+		for l := len(n.Weights) - 1; l >= 0; l-- {
+			in := outputs[l]
+			var previous []float64
+			if l > 0 {
+				previous = make([]float64, len(in))
+			}
+			for u, w := range n.Weights[l] {
+				for k, v := range in {
+					grads[l][u][k] += delta[u] * v
+					if previous != nil {
+						previous[k] += delta[u] * w[k]
+					}
+				}
+				grads[l][u][len(in)] += delta[u]
+			}
+			if l > 0 {
+				for k := range previous {
+					previous[k] *= derivatives[l-1][k]
+				}
+				delta = previous
+			}
+		}
+	}
+	m := float64(len(rows))
+	for l := range n.Weights {
+		for u, w := range n.Weights[l] {
+			for k := range w {
+				g := grads[l][u][k] / m
+				// biases are not penalized.
+				if k < len(w)-1 {
+					g += n.L2 * w[k]
+				}
+				w[k] -= n.LearningRate * g
+			}
+		}
+	}
+}
+
+// loss returns the weighted mean logistic loss of the training points of rows.
+func (n *MLP) loss(z [][]float64, rows []int) float64 {
+	var l, total float64
+	for _, i := range rows {
+		outputs, _ := n.forward(z[i])
+		l += n.Weight(i) * math.Log(1+math.Exp(-n.Yn[i]*outputs[len(outputs)-1][0]))
+		total += n.Weight(i)
+	}
+	if total == 0 {
+		return 0
+	}
+	return l / total
+}
+
+// Score returns the log odds of the label +1 for x, a point in the form
+// of the training points.
+func (n *MLP) Score(x []float64) float64 {
+	if len(n.Weights) == 0 {
+		return 0
+	}
+	outputs, _ := n.forward(n.standardize(x))
+	return outputs[len(outputs)-1][0]
+}
+
+// Predict returns +1 if the probability of the label +1 for x is at least 1/2.
+func (n *MLP) Predict(x []float64) float64 {
+	return classify.Sign(n.Score(x))
+}
+
+// Probability returns the probability of the label +1 for x.
+func (n *MLP) Probability(x []float64) float64 {
+	return classify.Logistic(n.Score(x))
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (n *MLP) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(n, &n.Data, x)
+}
+
+// Ein returns the in sample error.
+func (n *MLP) Ein() float64 {
+	return n.CachedEin(func() float64 { return classify.Ein(n, &n.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (n *MLP) Ecv() float64 {
+	return n.CachedEcv(func() float64 {
+		return classify.CrossValidation(&n.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *n
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
+
+func sigmoid(s float64) float64 {
+	return 1 / (1 + math.Exp(-s))
+}
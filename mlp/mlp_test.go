@@ -0,0 +1,73 @@
+package mlp
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns points labeled +1 inside the circle of radius 1 and -1
+// outside of it, which no line separates.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for len(data) < 300 {
+		a, b := r.Float64()*4-2, r.Float64()*4-2
+		y := -1.0
+		if a*a+b*b < 1 {
+			y = 1
+		}
+		data = append(data, []float64{a, b, y})
+	}
+	return
+}
+
+func TestMLPLearn(t *testing.T) {
+	for _, hidden := range [][]int{{16}, {8, 8}} {
+		for _, activation := range []string{ReLU, Tanh} {
+			n := NewMLP()
+			n.Hidden = hidden
+			n.Activation = activation
+			n.LearningRate = 0.1
+			n.Epochs = 300
+			n.BatchSize = 16
+			if err := n.InitializeFromData(points()); err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Learn(); err != nil {
+				t.Fatal(err)
+			}
+			if ein := n.Ein(); ein > 0.08 {
+				t.Errorf("%v %v: Ein = %v, want at most 0.08", hidden, activation, ein)
+			}
+			predictions, err := n.Predictions([][]float64{{0, 0}, {1.8, -1.8}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if predictions[0] != 1 || predictions[1] != -1 {
+				t.Errorf("%v %v: wrong predictions %v", hidden, activation, predictions)
+			}
+		}
+	}
+}
+
+func TestMLPEarlyStopping(t *testing.T) {
+	n := NewMLP()
+	n.LearningRate = 0.1
+	n.Epochs = 1000
+	n.EarlyStopping = 5
+	n.InitializeFromData(points())
+	if err := n.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if ein := n.Ein(); ein > 0.15 {
+		t.Errorf("Ein = %v, want at most 0.15", ein)
+	}
+	a := NewMLP()
+	a.InitializeFromData(points())
+	a.Learn()
+	b := NewMLP()
+	b.InitializeFromData(points())
+	b.Learn()
+	if a.Weights[0][0][0] != b.Weights[0][0][0] {
+		t.Error("two networks of the same seed have different weights")
+	}
+}
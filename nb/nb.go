@@ -0,0 +1,226 @@
+// Package nb implements a naive Bayes binary classifier for mixed inputs:
+// Gaussian likelihoods for continuous coordinates and categorical
+// likelihoods for discrete ones.
+package nb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// NaiveBayes is a naive Bayes classifier.
+// It predicts the label with the highest posterior probability, assuming
+// the coordinates of a point are independent given its label.
+type NaiveBayes struct {
+	classify.Data
+	Smoothing     float64 // additive smoothing of the categorical likelihoods.
+	MaxCategories int     // coordinates with at most this number of integer values are categorical, if Categorical is nil.
+	Categorical   []bool  // categorical coordinates. If nil they are detected, see MaxCategories.
+
+	Prior    [2]float64   // weight of the training points labeled -1 and +1, their number without weights.
+	Features []Likelihood // likelihood of each coordinate, x0 = 1 has none.
+}
+
+// Likelihood is the likelihood of a coordinate given each label, -1 first.
+type Likelihood struct {
+	Categorical bool         `json:",omitempty"`
+	Mean        [2]float64   `json:",omitempty"` // mean of a Gaussian coordinate.
+	Variance    [2]float64   `json:",omitempty"` // variance of a Gaussian coordinate.
+	Values      []float64    `json:",omitempty"` // values of a categorical coordinate.
+	Counts      [2][]float64 `json:",omitempty"` // weight of the training points of each value.
+}
+
+// NewNaiveBayes returns a naive Bayes classifier with a smoothing of 1,
+// coordinates of at most 10 integer values detected as categorical and 10
+// folds of cross validation.
+func NewNaiveBayes() *NaiveBayes {
+	return &NaiveBayes{
+		Data:          classify.Data{Folds: 10},
+		Smoothing:     1,
+		MaxCategories: 10,
+	}
+}
+
+// class returns the index of the label y in the arrays of likelihoods.
+func class(y float64) int {
+	if y == 1 {
+		return 1
+	}
+	return 0
+}
+
+// Learn estimates the prior and the likelihoods of each coordinate.
+func (nb *NaiveBayes) Learn() error {
+	nb.ResetErrors()
+	nb.Features = nil
+	if nb.Smoothing < 0 {
+		return fmt.Errorf("invalid smoothing %v", nb.Smoothing)
+	}
+	if len(nb.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+	if nb.Categorical != nil && len(nb.Categorical) != nb.VectorSize {
+		return fmt.Errorf("categorical coordinates %v for points of size %v", nb.Categorical, nb.VectorSize)
+	}
+
+	nb.Prior = [2]float64{}
+	for i, y := range nb.Yn {
+		nb.Prior[class(y)] += nb.Weight(i)
+	}
+	nb.Features = make([]Likelihood, nb.VectorSize)
+	for j := 1; j < nb.VectorSize; j++ {
+		if nb.categorical(j) {
+			nb.Features[j] = nb.categories(j)
+		} else {
+			nb.Features[j] = nb.gaussian(j)
+		}
+	}
+	return nil
+}
+
+// categorical returns true if the coordinate j is categorical.
+func (nb *NaiveBayes) categorical(j int) bool {
+	if nb.Categorical != nil {
+		return nb.Categorical[j]
+	}
+	values := make(map[float64]bool)
+	for _, x := range nb.Xn {
+		if x[j] != math.Trunc(x[j]) {
+			return false
+		}
+		if values[x[j]] = true; len(values) > nb.MaxCategories {
+			return false
+		}
+	}
+	return true
+}
+
+// categories returns the likelihood of the categorical coordinate j.
+func (nb *NaiveBayes) categories(j int) (l Likelihood) {
+	l.Categorical = true
+	index := make(map[float64]int)
+	for i, x := range nb.Xn {
+		k, ok := index[x[j]]
+		if !ok {
+			k = len(l.Values)
+			index[x[j]] = k
+			l.Values = append(l.Values, x[j])
+			l.Counts[0] = append(l.Counts[0], 0)
+			l.Counts[1] = append(l.Counts[1], 0)
+		}
+		l.Counts[class(nb.Yn[i])][k] += nb.Weight(i)
+	}
+	return
+}
+
+// gaussian returns the likelihood of the continuous coordinate j.
+func (nb *NaiveBayes) gaussian(j int) (l Likelihood) {
+	var all float64 // variance of the coordinate, for both labels.
+	var mean float64
+	for _, x := range nb.Xn {
+		mean += x[j]
+	}
+	mean /= float64(len(nb.Xn))
+	for _, x := range nb.Xn {
+		all += (x[j] - mean) * (x[j] - mean)
+	}
+	all /= float64(len(nb.Xn))
+
+	for i, x := range nb.Xn {
+		l.Mean[class(nb.Yn[i])] += nb.Weight(i) * x[j]
+	}
+	for c := range l.Mean {
+		if nb.Prior[c] > 0 {
+			l.Mean[c] /= nb.Prior[c]
+		}
+	}
+	for i, x := range nb.Xn {
+		c := class(nb.Yn[i])
+		l.Variance[c] += nb.Weight(i) * (x[j] - l.Mean[c]) * (x[j] - l.Mean[c])
+	}
+	for c := range l.Variance {
+		if nb.Prior[c] > 0 {
+			l.Variance[c] /= nb.Prior[c]
+		}
+		// a coordinate constant within a label still has a density.
+		l.Variance[c] += 1e-9*all + 1e-9
+	}
+	return
+}
+
+// logLikelihood returns the log of the likelihood of v given the label of index c.
+func (nb *NaiveBayes) logLikelihood(l Likelihood, c int, v float64) float64 {
+	if !l.Categorical {
+		d := v - l.Mean[c]
+		return -0.5*math.Log(2*math.Pi*l.Variance[c]) - d*d/(2*l.Variance[c])
+	}
+	count := 0.0
+	for k, value := range l.Values {
+		if value == v {
+			count = l.Counts[c][k]
+			break
+		}
+	}
+	// one more category stands for the values not seen in training.
+	p := (count + nb.Smoothing) / (nb.Prior[c] + nb.Smoothing*float64(len(l.Values)+1))
+	if p == 0 {
+		return math.Inf(-1)
+	}
+	return math.Log(p)
+}
+
+// Score returns the log odds of the label +1 for x.
+func (nb *NaiveBayes) Score(x []float64) float64 {
+	var s [2]float64
+	total := nb.Prior[0] + nb.Prior[1]
+	for c := range s {
+		s[c] = math.Log(nb.Prior[c] / total)
+		for j := 1; j < len(nb.Features) && j < len(x); j++ {
+			s[c] += nb.logLikelihood(nb.Features[j], c, x[j])
+		}
+	}
+	if math.IsInf(s[0], -1) && math.IsInf(s[1], -1) {
+		return 0
+	}
+	return s[1] - s[0]
+}
+
+// Predict returns the label with the highest posterior probability,
+// +1 in case of a tie.
+func (nb *NaiveBayes) Predict(x []float64) float64 {
+	if len(nb.Features) == 0 {
+		return 1
+	}
+	return classify.Sign(nb.Score(x))
+}
+
+// Probability returns the posterior probability of the label +1 for x.
+func (nb *NaiveBayes) Probability(x []float64) float64 {
+	if len(nb.Features) == 0 {
+		return 0.5
+	}
+	return classify.Logistic(nb.Score(x))
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (nb *NaiveBayes) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(nb, &nb.Data, x)
+}
+
+// Ein returns the in sample error.
+func (nb *NaiveBayes) Ein() float64 {
+	return nb.CachedEin(func() float64 { return classify.Ein(nb, &nb.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (nb *NaiveBayes) Ecv() float64 {
+	return nb.CachedEcv(func() float64 {
+		return classify.CrossValidation(&nb.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *nb
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package nb
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns points with a categorical coordinate, 0 or 1, that is
+// mostly the label and a continuous one centered on 2 times the label.
+func points() (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 200; i++ {
+		y := float64(2*(i%2) - 1)
+		category := (y + 1) / 2
+		if r.Float64() < 0.1 {
+			category = 1 - category
+		}
+		data = append(data, []float64{category, 2*y + r.NormFloat64()*0.5, y})
+	}
+	return
+}
+
+func TestNaiveBayesLearn(t *testing.T) {
+	nb := NewNaiveBayes()
+	if err := nb.InitializeFromData(points()); err != nil {
+		t.Fatal(err)
+	}
+	if err := nb.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if !nb.Features[1].Categorical || nb.Features[2].Categorical {
+		t.Errorf("wrong likelihoods, got categorical %v and %v", nb.Features[1].Categorical, nb.Features[2].Categorical)
+	}
+	if ein := nb.Ein(); ein > 0.05 {
+		t.Errorf("Ein = %v, want at most 0.05", ein)
+	}
+	if ecv := nb.Ecv(); ecv > 0.05 {
+		t.Errorf("Ecv = %v, want at most 0.05", ecv)
+	}
+	// an unseen category is smoothed instead of ruling a label out.
+	predictions, err := nb.Predictions([][]float64{{7, 2}, {7, -2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if predictions[0] != 1 || predictions[1] != -1 {
+		t.Errorf("wrong predictions %v", predictions)
+	}
+}
+
+func TestNaiveBayesCategorical(t *testing.T) {
+	nb := NewNaiveBayes()
+	nb.InitializeFromData(points())
+	nb.Categorical = []bool{false, false, false}
+	if err := nb.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if nb.Features[1].Categorical {
+		t.Error("coordinate 1 should be Gaussian when set explicitly")
+	}
+	nb.Categorical = []bool{false}
+	if err := nb.Learn(); err == nil {
+		t.Error("expected an error when the categorical coordinates do not match the points")
+	}
+}
+
+func TestNaiveBayesWeights(t *testing.T) {
+	nb := NewNaiveBayes()
+	nb.InitializeFromData(points())
+	if err := nb.SetWeights([]float64{1}); err == nil {
+		t.Error("expected an error when the weights do not match the points")
+	}
+	w := make([]float64, len(nb.Yn))
+	for i, y := range nb.Yn {
+		w[i] = 1
+		if y == 1 {
+			w[i] = 3
+		}
+	}
+	if err := nb.SetWeights(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := nb.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if nb.Prior != [2]float64{100, 300} {
+		t.Errorf("Prior = %v, want [100 300]", nb.Prior)
+	}
+}
@@ -0,0 +1,143 @@
+// Package pla implements the perceptron learning algorithm and its pocket
+// variant for non separable data.
+package pla
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Algorithms learning the weights of a perceptron.
+const (
+	PLA    = "pla"    // keep the last weights.
+	Pocket = "pocket" // keep the weights with the lowest in sample error.
+)
+
+// Perceptron is a linear classifier sign(Wn . x).
+type Perceptron struct {
+	classify.Data
+	Algorithm     string // PLA or Pocket.
+	MaxIterations int    // maximum number of updates of the weights.
+	Seed          int64  // seed of the choice of the misclassified point of each update.
+
+	Wn         []float64 // learned weights.
+	Converged  bool      // true if Wn separates the training points.
+	Iterations int       // number of updates done.
+}
+
+// NewPerceptron returns a pocket perceptron of at most 1000 iterations,
+// seed 1 and 10 folds of cross validation.
+func NewPerceptron() *Perceptron {
+	return &Perceptron{
+		Data:          classify.Data{Folds: 10},
+		Algorithm:     Pocket,
+		MaxIterations: 1000,
+		Seed:          1,
+	}
+}
+
+// Learn updates the weights with a misclassified point, picked at random,
+// until every training point is classified correctly or MaxIterations
+// updates are done. Each update is scaled by the weight of the point and
+// the pocket keeps the weights with the lowest weight of misclassified
+// points.
+func (p *Perceptron) Learn() error {
+	p.ResetErrors()
+	if p.Algorithm != PLA && p.Algorithm != Pocket {
+		return fmt.Errorf("unknown algorithm %v", p.Algorithm)
+	}
+	if p.MaxIterations < 0 {
+		return fmt.Errorf("invalid number of iterations %v", p.MaxIterations)
+	}
+	if len(p.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+
+	r := rand.New(rand.NewSource(p.Seed))
+	w := make([]float64, p.VectorSize)
+	misclassified := p.misclassified(w, nil)
+	best := append([]float64(nil), w...)
+	bestErrors := p.weight(misclassified)
+
+	p.Converged = false
+	for p.Iterations = 0; p.Iterations < p.MaxIterations && len(misclassified) > 0; p.Iterations++ {
+		i := misclassified[r.Intn(len(misclassified))]
+		for j, x := range p.Xn[i] {
+			w[j] += p.Weight(i) * p.Yn[i] * x
+		}
+		misclassified = p.misclassified(w, misclassified)
+		if errors := p.weight(misclassified); errors < bestErrors {
+			bestErrors = errors
+			copy(best, w)
+		}
+	}
+	p.Converged = len(misclassified) == 0
+	p.Wn = w
+	if p.Algorithm == Pocket {
+		p.Wn = best
+	}
+	return nil
+}
+
+// weight returns the weight of the training points of rows.
+func (p *Perceptron) weight(rows []int) (w float64) {
+	for _, i := range rows {
+		w += p.Weight(i)
+	}
+	return
+}
+
+// misclassified returns the training points not strictly on the side of
+// their label for the weights w, reusing the memory of rows.
+func (p *Perceptron) misclassified(w []float64, rows []int) []int {
+	rows = rows[:0]
+	for i, x := range p.Xn {
+		if p.Yn[i]*dot(w, x) <= 0 {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+func dot(a, b []float64) (d float64) {
+	for i := range a {
+		if i < len(b) {
+			d += a[i] * b[i]
+		}
+	}
+	return
+}
+
+// Predict returns the sign of Wn . x.
+func (p *Perceptron) Predict(x []float64) float64 {
+	return classify.Sign(p.Score(x))
+}
+
+// Score returns the margin Wn . x.
+func (p *Perceptron) Score(x []float64) float64 {
+	return dot(p.Wn, x)
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (p *Perceptron) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(p, &p.Data, x)
+}
+
+// Ein returns the in sample error.
+func (p *Perceptron) Ein() float64 {
+	return p.CachedEin(func() float64 { return classify.Ein(p, &p.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (p *Perceptron) Ecv() float64 {
+	return p.CachedEcv(func() float64 {
+		return classify.CrossValidation(&p.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *p
+			c.Data = d
+			c.Wn = nil
+			return &c, c.Learn()
+		})
+	})
+}
@@ -0,0 +1,66 @@
+package pla
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// points returns points labeled by the side of the line a + b = 1 they
+// are on, flipping the label of a point out of flip.
+func points(flip int) (data [][]float64) {
+	r := rand.New(rand.NewSource(7))
+	for i := 0; i < 100; i++ {
+		a, b := r.Float64()*4-2, r.Float64()*4-2
+		if d := a + b - 1; d < 0.1 && d > -0.1 {
+			continue
+		}
+		y := -1.0
+		if a+b > 1 {
+			y = 1
+		}
+		if flip > 0 && i%flip == 0 {
+			y = -y
+		}
+		data = append(data, []float64{a, b, y})
+	}
+	return
+}
+
+func TestPLASeparable(t *testing.T) {
+	p := NewPerceptron()
+	p.Algorithm = PLA
+	p.MaxIterations = 10000
+	if err := p.InitializeFromData(points(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Converged {
+		t.Errorf("PLA did not converge in %v iterations", p.Iterations)
+	}
+	if ein := p.Ein(); ein != 0 {
+		t.Errorf("Ein = %v, want 0", ein)
+	}
+}
+
+func TestPocketNonSeparable(t *testing.T) {
+	data := points(10)
+	pocket, last := NewPerceptron(), NewPerceptron()
+	last.Algorithm = PLA
+	for _, p := range []*Perceptron{pocket, last} {
+		p.InitializeFromData(data)
+		if err := p.Learn(); err != nil {
+			t.Fatal(err)
+		}
+		if p.Converged || p.Iterations != p.MaxIterations {
+			t.Errorf("%v: converged %v after %v iterations on non separable data", p.Algorithm, p.Converged, p.Iterations)
+		}
+	}
+	if pocket.Ein() > last.Ein() {
+		t.Errorf("pocket Ein = %v is worse than PLA Ein = %v", pocket.Ein(), last.Ein())
+	}
+	if ein := pocket.Ein(); ein > 0.2 {
+		t.Errorf("pocket Ein = %v, want at most 0.2", ein)
+	}
+}
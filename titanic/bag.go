@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/santiaago/kaggle/bag"
+	"github.com/santiaago/ml"
+)
+
+// bagged is a bag of models of another family, the bagged family.
+type bagged struct {
+	*bag.Bag
+	base modelInfo // model info of the members.
+}
+
+// bagState is the learned state of a bag.
+type bagState struct {
+	Seeds   []int64
+	Members []json.RawMessage // learned state of each member, see modelFamily.marshal.
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   bagging,
+		name:    "bag",
+		enabled: trainBag,
+		params: []hyperparameter{
+			{"family", "name of the bagged family."},
+			{"bags", "number of bootstrap samples, that is of members."},
+			{"bagSeed", "seed of the bootstrap samples."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*bagged)
+			return ok
+		},
+		hyperparameters: bagHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			b := &bagged{Bag: bag.NewBag()}
+			b.Folds = *folds
+			b.Bags = mi.intParam("bags", b.Bags)
+			b.Seed = int64(mi.intParam("bagSeed", int(b.Seed)))
+			f, base, err := baggedInfo(mi)
+			if err != nil {
+				log.Println(err)
+				return b
+			}
+			b.base = base
+			b.Fit = func(fd [][]float64) (bag.Model, error) {
+				// the bootstrap samples are drawn by weight.
+				return f.fit(base, fd, nil)
+			}
+			return b
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			// the members transform their own data.
+			return m.(*bagged).InitializeFromData(fd)
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*bagged).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*bagged).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*bagged).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*bagged).scores(x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			b := m.(*bagged)
+			mi.Regularized, mi.K, mi.T, mi.L = b.base.Regularized, b.base.K, b.base.T, b.base.L
+			for name, v := range b.base.Params {
+				mi.setParam(name, v)
+			}
+			if f := familyOf(b.base.Model); f != nil {
+				mi.setParam("family", f.name)
+			}
+			mi.setParam("bags", b.Bags)
+			mi.setParam("bagSeed", b.Seed)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			b := m.(*bagged)
+			f := familyOf(b.base.Model)
+			if f == nil {
+				return nil, fmt.Errorf("unknown bagged model type %v", b.base.Model)
+			}
+			st := bagState{Seeds: b.Seeds}
+			for _, member := range b.Members {
+				s, err := f.marshal(member.(ml.Model))
+				if err != nil {
+					return nil, err
+				}
+				st.Members = append(st.Members, s)
+			}
+			return json.Marshal(st)
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			b := m.(*bagged)
+			var st bagState
+			if err := json.Unmarshal(state, &st); err != nil {
+				return err
+			}
+			f, base, err := baggedInfo(mi)
+			if err != nil {
+				return err
+			}
+			if len(st.Seeds) != len(st.Members) {
+				return fmt.Errorf("bag with %v seeds and %v members", len(st.Seeds), len(st.Members))
+			}
+			b.ResetErrors()
+			b.Seeds = st.Seeds
+			// the members of an initialized bag are initialized on their
+			// bootstrap sample, some of them predict from their training points.
+			members := make([]bag.Model, len(st.Members))
+			for i, s := range st.Members {
+				member := f.newModel(base)
+				var err error
+				if len(b.Xn) > 0 {
+					err = f.restore(member, base, b.Sample(i), nil, s)
+				} else {
+					err = f.unmarshal(member, base, s)
+				}
+				if err != nil {
+					return err
+				}
+				members[i] = member
+			}
+			b.Members = members
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*bagged).Members) > 0
+		},
+		label: func(mi modelInfo) string {
+			f, base, err := baggedInfo(mi)
+			if err != nil {
+				return ""
+			}
+			var label string
+			if f.label != nil {
+				label = f.label(base)
+			}
+			return label + f.paramsLabel(base)
+		},
+		override: func(mi modelInfo) modelInfo {
+			if f, _, err := baggedInfo(mi); err == nil && f.override != nil {
+				return f.override(mi)
+			}
+			return mi
+		},
+		wrapped: func(mi modelInfo) *modelFamily {
+			f, _, _ := baggedInfo(mi)
+			return f
+		},
+	})
+}
+
+// scores returns the mean of the probabilities of the label +1 given by
+// the members or, when the bagged family is not probabilistic, the fraction
+// of the members that predict +1.
+func (b *bagged) scores(x [][]float64) ([]float64, error) {
+	f := familyOf(b.base.Model)
+	if f == nil || len(b.Members) == 0 {
+		return nil, fmt.Errorf("bag not trained")
+	}
+	scores := make([]float64, len(x))
+	for _, member := range b.Members {
+		s, err := f.scores(member.(ml.Model), x)
+		if err != nil {
+			return nil, err
+		}
+		for j, v := range s {
+			if f.probabilistic {
+				scores[j] += v
+			} else if v >= 0 {
+				scores[j]++
+			}
+		}
+	}
+	for j := range scores {
+		scores[j] /= float64(len(b.Members))
+	}
+	return scores, nil
+}
+
+// baggedInfo returns the bagged family of the bag described by the model
+// info passed in and the model info of its members: the model info of the
+// bag with the model type of the bagged family, without the
+// hyperparameters of the bag.
+//
+func baggedInfo(mi modelInfo) (f *modelFamily, base modelInfo, err error) {
+	name := mi.param("family", "")
+	if f = familyNamed(name); f == nil || f.model == bagging {
+		return nil, base, fmt.Errorf("invalid bagged family %v", name)
+	}
+	base = mi
+	base.Model = f.model
+	base.State = nil
+	base.Params = nil
+	for k, v := range mi.Params {
+		if k != "family" && k != "bags" && k != "bagSeed" {
+			base.setParam(k, v)
+		}
+	}
+	return f, base, nil
+}
+
+// bagHyperparameters returns a model info for every setting of each
+// family of the bagFamily flag and every number of bags.
+//
+func bagHyperparameters() (mis []modelInfo) {
+	sizes, err := intRange(*bagSize)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, name := range strings.Split(*bagFamily, ",") {
+		name = strings.TrimSpace(name)
+		f := familyNamed(name)
+		if f == nil || f.model == bagging {
+			log.Printf("invalid bagged family %v", name)
+			return nil
+		}
+		for _, base := range f.hyperparameters() {
+			for _, n := range sizes {
+				mi := base
+				mi.Model = bagging
+				mi.setParam("family", f.name)
+				mi.setParam("bags", n)
+				mi.setParam("bagSeed", *bagSeed)
+				mis = append(mis, mi)
+			}
+		}
+	}
+	return
+}
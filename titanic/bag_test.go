@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/santiaago/ml"
+)
+
+func TestBagExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bags.json")
+
+	defer func(family, size string, export bool) {
+		*bagFamily, *bagSize, *canExportModels = family, size, export
+	}(*bagFamily, *bagSize, *canExportModels)
+	*bagFamily, *bagSize, *canExportModels = "knn,tree", "3", true
+
+	dc := smallContainer()
+	f := familyNamed("bag")
+	var bags ml.ModelContainers
+	for _, mi := range f.hyperparameters() {
+		mi.Features = []int{0, 1}
+		mc, err := f.container(mi, dc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bags = append(bags, mc)
+	}
+
+	exportModels(bags, path)
+	imported := updateModels(dc, importModels(path))
+	if len(imported) != len(bags) {
+		t.Fatalf("imported %v bags, want %v", len(imported), len(bags))
+	}
+	x := dc.Filter([]int{0, 1})
+	for i, mc := range imported {
+		if !f.learned(mc.Model) {
+			t.Errorf("bag %v was imported without its members", mc.Name)
+			continue
+		}
+		want, err := f.predict(bags[i].Model, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.predict(mc.Model, x)
+		if err != nil {
+			t.Errorf("imported bag %v cannot predict, %v", mc.Name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("imported bag %v predicts %v, want %v", mc.Name, got, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/santiaago/kaggle/boost"
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/ml"
+)
+
+// boostState is the learned state of a gradient boosting classifier.
+type boostState struct {
+	F0     float64
+	Stages []*boost.Node
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   gradientBoosting,
+		name:    "boost",
+		enabled: trainBoost,
+		params: []hyperparameter{
+			{"rounds", "maximum number of trees."},
+			{"rate", "learning rate, shrinkage of the value of each tree."},
+			{"depth", "maximum depth of the trees."},
+			{"minLeaf", "minimum number of training points in a leaf."},
+			{"subsample", "fraction of the training points used to fit each tree."},
+			{"stop", "rounds without improvement of the validation loss before stopping, 0 disables it."},
+			{"seed", "seed of the subsamples."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*boost.Boost)
+			return ok
+		},
+		hyperparameters: boostHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			b := boost.NewBoost()
+			b.TransformFunction, b.HasTransform = mi.transformFunction()
+			b.Folds = *folds
+			b.Rounds = mi.intParam("rounds", b.Rounds)
+			b.LearningRate = mi.floatParam("rate", b.LearningRate)
+			b.MaxDepth = mi.intParam("depth", b.MaxDepth)
+			b.MinLeaf = mi.intParam("minLeaf", b.MinLeaf)
+			b.Subsample = mi.floatParam("subsample", b.Subsample)
+			b.EarlyStopping = mi.intParam("stop", b.EarlyStopping)
+			b.Seed = int64(mi.intParam("seed", int(b.Seed)))
+			return b
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			b := m.(*boost.Boost)
+			if err := b.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if b.HasTransform {
+				return b.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*boost.Boost).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*boost.Boost).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*boost.Boost).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			b := m.(*boost.Boost)
+			return classify.Scores(b.Probability, &b.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			b := m.(*boost.Boost)
+			mi.setParam("rounds", b.Rounds)
+			mi.setParam("rate", b.LearningRate)
+			mi.setParam("depth", b.MaxDepth)
+			mi.setParam("minLeaf", b.MinLeaf)
+			mi.setParam("subsample", b.Subsample)
+			mi.setParam("stop", b.EarlyStopping)
+			mi.setParam("seed", b.Seed)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			b := m.(*boost.Boost)
+			return json.Marshal(boostState{b.F0, b.Stages})
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			b := m.(*boost.Boost)
+			var s boostState
+			if err := json.Unmarshal(state, &s); err != nil {
+				return err
+			}
+			b.ResetErrors()
+			b.F0, b.Stages = s.F0, s.Stages
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*boost.Boost).Stages) > 0
+		},
+	})
+}
+
+// boostHyperparameters returns a model info for every combination of the
+// values of the boosting flags.
+//
+func boostHyperparameters() (mis []modelInfo) {
+	rounds, err := intRange(*boostRounds)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	rates, err := floatList(*boostRate)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	depths, err := intRange(*boostDepth)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	subsamples, err := floatList(*boostSubsample)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, n := range rounds {
+		for _, r := range rates {
+			for _, d := range depths {
+				for _, s := range subsamples {
+					mi := modelInfo{Model: gradientBoosting}
+					mi.setParam("rounds", n)
+					mi.setParam("rate", r)
+					mi.setParam("depth", d)
+					mi.setParam("minLeaf", *boostMinLeaf)
+					mi.setParam("subsample", s)
+					mi.setParam("stop", *boostStop)
+					mi.setParam("seed", *boostSeed)
+					mis = append(mis, mi)
+				}
+			}
+		}
+	}
+	return
+}
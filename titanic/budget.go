@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/santiaago/ml"
+)
+
+// errFitTimeout is returned when a fit takes longer than the fitTimeout flag.
+var errFitTimeout = errors.New("fit timed out")
+
+// timedOutFit describes a fit abandoned because it exceeded its time budget.
+type timedOutFit struct {
+	Name  string // the name of the model.
+	Stage string // the training stage of the fit.
+}
+
+// timeoutList holds the fits that timed out during the training.
+type timeoutList struct {
+	sync.Mutex
+	fits []timedOutFit
+}
+
+// timeouts are the fits of the current run that timed out.
+var timeouts timeoutList
+
+// add records a fit of the stage and the model info passed in as timed out.
+func (t *timeoutList) add(stage string, mi modelInfo) {
+	// the k of a regularized model is only known once it is trained.
+	regularized := mi.Regularized
+	mi.Regularized = false
+	name := mi.name()
+	if regularized {
+		name += " regularized"
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	t.fits = append(t.fits, timedOutFit{name, stage})
+}
+
+// list returns the fits that timed out.
+func (t *timeoutList) list() []timedOutFit {
+	t.Lock()
+	defer t.Unlock()
+	return append([]timedOutFit(nil), t.fits...)
+}
+
+// fitsInFlight bounds the fits running at once, abandoned ones included,
+// so that fits that keep timing out cannot pile up in the background.
+var fitsInFlight = make(chan struct{}, runtime.NumCPU())
+
+// trainWithinBudget returns the model trained by the train function.
+// If the fit takes longer than the fitTimeout flag it is abandoned and
+// errFitTimeout is returned; if the training is interrupted or the deadline
+// is reached while fitting, the fit is abandoned as well.
+// The context passed to the train function is done when the fit is
+// abandoned. The models cannot be stopped while they learn, so an abandoned
+// fit keeps running in the background until its current model is trained,
+// but a fit made of several models, like a regularization sweep, stops
+// before training the next one. Its result is discarded.
+// At most fitsInFlight fits run at once: when the abandoned fits fill it,
+// the next fit waits for one of them to end before its budget starts.
+func trainWithinBudget(train func(ctx context.Context) (*ml.ModelContainer, error)) (*ml.ModelContainer, error) {
+	if *fitTimeout <= 0 && *deadline <= 0 {
+		return train(trainCtx)
+	}
+
+	select {
+	case fitsInFlight <- struct{}{}:
+	case <-trainCtx.Done():
+		return nil, trainCtx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(trainCtx)
+	if *fitTimeout > 0 {
+		ctx, cancel = context.WithTimeout(trainCtx, *fitTimeout)
+	}
+	defer cancel()
+
+	type result struct {
+		mc  *ml.ModelContainer
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() { <-fitsInFlight }()
+		mc, err := train(ctx)
+		done <- result{mc, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.mc, r.err
+	case <-ctx.Done():
+		if err := trainCtx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errFitTimeout
+	}
+}
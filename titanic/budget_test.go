@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/santiaago/ml"
+)
+
+func TestTrainWithinBudgetBoundsAbandonedFits(t *testing.T) {
+	defer func(d time.Duration) { *fitTimeout = d }(*fitTimeout)
+	*fitTimeout = 10 * time.Millisecond
+
+	release := make(chan struct{})
+	stuck := func(ctx context.Context) (*ml.ModelContainer, error) {
+		<-release
+		return nil, nil
+	}
+	for i := 0; i < cap(fitsInFlight); i++ {
+		if _, err := trainWithinBudget(stuck); err != errFitTimeout {
+			t.Fatalf("trainWithinBudget() of a stuck fit returned %v, want %v", err, errFitTimeout)
+		}
+	}
+
+	started := make(chan struct{})
+	go trainWithinBudget(func(ctx context.Context) (*ml.ModelContainer, error) {
+		close(started)
+		return nil, nil
+	})
+	select {
+	case <-started:
+		t.Fatal("a fit started while the abandoned fits fill every slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("a fit did not start once the abandoned fits ended")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// modelCache is an on-disk cache of trained models shared by every run.
+// Each model is stored in its own file named after a hash of the
+// configuration of the model and of the training data, so a configuration
+// trained once on some data is never trained again on the same data.
+type modelCache struct {
+	sync.Mutex
+	dir    string
+	data   string // hash of the training data.
+	hits   int
+	misses int
+	writes int
+}
+
+// cache is the model cache of the current run, see openCache.
+// It is nil when the cache is disabled.
+var cache *modelCache
+
+// openCache sets up the model cache of the current run for the training
+// data passed in. An empty cache flag disables the cache.
+func openCache(dc data.Container) {
+	cache = nil
+	if *cachePath == "" {
+		return
+	}
+	if err := os.MkdirAll(*cachePath, 0777); err != nil {
+		log.Printf("unable to create cache folder %v, %v", *cachePath, err)
+		return
+	}
+	cache = &modelCache{dir: *cachePath, data: dataHash(dc)}
+}
+
+// path returns the path of the cache file of the key passed in.
+func (c *modelCache) path(key string) string {
+	sum := sha256.Sum256([]byte(c.data + "\n" + key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached model of the key passed in rebuilt on the data
+// container passed in.
+func (c *modelCache) get(key string, dc data.Container) (*ml.ModelContainer, bool) {
+	if c == nil {
+		return nil, false
+	}
+	path := c.path(key)
+	mc, err := readCacheEntry(path, key, dc)
+	c.Lock()
+	defer c.Unlock()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("skipping cache entry %v, %v", path, err)
+		}
+		c.misses++
+		return nil, false
+	}
+	// the modification time tells when an entry was last used, see pruneCache.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	c.hits++
+	return mc, true
+}
+
+// readCacheEntry reads the cache file at path and rebuilds its model.
+func readCacheEntry(path, key string, dc data.Container) (*ml.ModelContainer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e checkpointEntry
+	if err = json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if e.Key != key {
+		return nil, fmt.Errorf("key mismatch, found %v", e.Key)
+	}
+	return e.model(dc)
+}
+
+// put writes the entry of a trained model to the cache, see newCheckpointEntry.
+// The entry is written to a temporary file first so that a cache file is
+// never read half written.
+func (c *modelCache) put(e checkpointEntry) {
+	if c == nil {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("unable to marshal cache entry %v, %v", e.Name, err)
+		return
+	}
+	path := c.path(e.Key)
+	tmp, err := ioutil.TempFile(c.dir, "entry")
+	if err != nil {
+		log.Printf("unable to write cache entry %v, %v", e.Name, err)
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("unable to write cache entry %v, %v", e.Name, err)
+		return
+	}
+	c.Lock()
+	c.writes++
+	c.Unlock()
+}
+
+// report prints the statistics of the cache for the current run.
+func (c *modelCache) report() {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	rate := 0.0
+	if total := c.hits + c.misses; total > 0 {
+		rate = 100 * float64(c.hits) / float64(total)
+	}
+	fmt.Printf("cache %v: %v hits, %v misses (%.0f%% hit rate), %v writes\n", c.dir, c.hits, c.misses, rate, c.writes)
+}
+
+// cacheFile is a file of the cache folder.
+type cacheFile struct {
+	path string
+	size int64
+	used time.Time
+}
+
+// cacheFiles returns the entries of the cache folder passed in,
+// least recently used first.
+func cacheFiles(dir string) (files []cacheFile, err error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range infos {
+		if fi.IsDir() || filepath.Ext(fi.Name()) != ".json" {
+			continue
+		}
+		files = append(files, cacheFile{filepath.Join(dir, fi.Name()), fi.Size(), fi.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	return
+}
+
+// pruneCache removes the entries of the cache folder passed in that have
+// not been used for longer than maxAge, then removes the least recently
+// used entries until the folder holds at most maxSize bytes.
+// A maxAge or a maxSize of 0 is not a limit.
+func pruneCache(dir string, maxAge time.Duration, maxSize int64) (removed int, freed int64, err error) {
+	files, err := cacheFiles(dir)
+	if err != nil {
+		return 0, 0, err
+	}
+	var size int64
+	for _, f := range files {
+		size += f.size
+	}
+	for _, f := range files {
+		old := maxAge > 0 && time.Since(f.used) > maxAge
+		big := maxSize > 0 && size > maxSize
+		if !old && !big {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			log.Println(err)
+			continue
+		}
+		removed++
+		freed += f.size
+		size -= f.size
+	}
+	return
+}
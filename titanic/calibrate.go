@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// Calibrations of the scores of the models.
+const (
+	plattScaling       = "platt"    // logistic function of the score.
+	isotonicRegression = "isotonic" // non decreasing step function of the score.
+)
+
+// calibrator maps the scores of a model to probabilities of the label +1.
+type calibrator interface {
+	probability(score float64) float64
+}
+
+// platt is the Platt scaling 1 / (1 + e^(A s + B)) of a score s.
+type platt struct {
+	A, B float64
+}
+
+func (p platt) probability(s float64) float64 {
+	return 1 / (1 + math.Exp(p.A*s+p.B))
+}
+
+// fitPlatt returns the Platt scaling of the scores passed in fitted to
+// their labels, -1 or +1. It minimizes the logistic loss with the smoothed
+// targets of Platt by Newton's method with backtracking, see Lin, Lin and
+// Weng, a note on Platt's probabilistic outputs for support vector machines.
+func fitPlatt(scores, labels []float64) platt {
+	var positives, negatives float64
+	for _, y := range labels {
+		if y > 0 {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	targets := make([]float64, len(labels))
+	for i, y := range labels {
+		targets[i] = 1 / (negatives + 2)
+		if y > 0 {
+			targets[i] = (positives + 1) / (positives + 2)
+		}
+	}
+	loss := func(a, b float64) (l float64) {
+		for i, s := range scores {
+			// stable form of the cross entropy of the target and the probability.
+			if z := a*s + b; z >= 0 {
+				l += targets[i]*z + math.Log(1+math.Exp(-z))
+			} else {
+				l += (targets[i]-1)*z + math.Log(1+math.Exp(z))
+			}
+		}
+		return
+	}
+
+	p := platt{0, math.Log((negatives + 1) / (positives + 1))}
+	l := loss(p.A, p.B)
+	for iteration := 0; iteration < 100; iteration++ {
+		h11, h22, h21, g1, g2 := 1e-12, 1e-12, 0.0, 0.0, 0.0
+		for i, s := range scores {
+			q := p.probability(s)
+			d2 := q * (1 - q)
+			h11 += s * s * d2
+			h22 += d2
+			h21 += s * d2
+			d1 := targets[i] - q
+			g1 += s * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < 1e-5 && math.Abs(g2) < 1e-5 {
+			break
+		}
+		det := h11*h22 - h21*h21
+		da := -(h22*g1 - h21*g2) / det
+		db := -(-h21*g1 + h11*g2) / det
+		gd := g1*da + g2*db
+		step := 1.0
+		for ; step >= 1e-10; step /= 2 {
+			a, b := p.A+step*da, p.B+step*db
+			if nl := loss(a, b); nl < l+1e-4*step*gd {
+				p, l = platt{a, b}, nl
+				break
+			}
+		}
+		if step < 1e-10 {
+			break
+		}
+	}
+	return p
+}
+
+// isotonic is a non decreasing step function from scores to probabilities.
+type isotonic struct {
+	Scores        []float64 // highest score of each step, increasing.
+	Probabilities []float64 // probability of each step, non decreasing.
+}
+
+func (c isotonic) probability(s float64) float64 {
+	if len(c.Scores) == 0 {
+		return 0.5
+	}
+	i := sort.SearchFloat64s(c.Scores, s)
+	if i == len(c.Scores) {
+		i--
+	}
+	return c.Probabilities[i]
+}
+
+// fitIsotonic returns the isotonic regression of the labels, -1 or +1,
+// on the scores passed in, by the pool adjacent violators algorithm.
+// Points of the same score are in the same step.
+func fitIsotonic(scores, labels []float64) (c isotonic) {
+	rows := make([]int, len(scores))
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.Slice(rows, func(a, b int) bool { return scores[rows[a]] < scores[rows[b]] })
+
+	var counts []float64
+	for k, i := range rows {
+		y := 0.0
+		if labels[i] > 0 {
+			y = 1
+		}
+		if k > 0 && scores[i] == c.Scores[len(c.Scores)-1] {
+			last := len(c.Scores) - 1
+			c.Probabilities[last] += y
+			counts[last]++
+		} else {
+			c.Scores = append(c.Scores, scores[i])
+			c.Probabilities = append(c.Probabilities, y)
+			counts = append(counts, 1)
+		}
+		// merge the last steps while their means decrease.
+		for last := len(c.Scores) - 1; last > 0 && c.Probabilities[last-1]*counts[last] > c.Probabilities[last]*counts[last-1]; last-- {
+			c.Scores[last-1] = c.Scores[last]
+			c.Probabilities[last-1] += c.Probabilities[last]
+			counts[last-1] += counts[last]
+			c.Scores, c.Probabilities, counts = c.Scores[:last], c.Probabilities[:last], counts[:last]
+		}
+	}
+	for i := range c.Probabilities {
+		c.Probabilities[i] /= counts[i]
+	}
+	return
+}
+
+// fitCalibrator returns the calibration passed in of the scores fitted to
+// their labels.
+func fitCalibrator(calibration string, scores, labels []float64) (calibrator, error) {
+	switch calibration {
+	case plattScaling:
+		return fitPlatt(scores, labels), nil
+	case isotonicRegression:
+		return fitIsotonic(scores, labels), nil
+	}
+	return nil, fmt.Errorf("unknown calibration %v", calibration)
+}
+
+// linearScores returns the margin w . x of each point of x, with x0 = 1
+// and transformed, for the linear models of the ml package.
+func linearScores(w []float64, transform func([]float64) ([]float64, error), hasTransform bool, x [][]float64) ([]float64, error) {
+	scores := make([]float64, len(x))
+	for i, row := range x {
+		p := append([]float64{1}, row...)
+		if hasTransform {
+			var err error
+			if p, err = transform(p); err != nil {
+				return nil, err
+			}
+		}
+		if len(p) != len(w) {
+			return nil, fmt.Errorf("point of size %v for weights of size %v", len(p), len(w))
+		}
+		for j := range p {
+			scores[i] += w[j] * p[j]
+		}
+	}
+	return scores, nil
+}
+
+// outOfFoldScores returns the scores of the training points of dc, each
+// one by the model passed in trained again on the other folds, see
+// classify.Folds, and their labels.
+func outOfFoldScores(mc *ml.ModelContainer, dc data.Container) (scores, labels []float64, err error) {
+	f := familyOfModel(mc.Model)
+	if f == nil {
+		return nil, nil, fmt.Errorf("model %v has no model family", mc.Name)
+	}
+	fd := dc.FilterWithPredict(mc.Features)
+	w := rowWeights(dc)
+	mi := ModelInfoFromModel(mc)
+	scores = make([]float64, len(fd))
+	labels = make([]float64, len(fd))
+	for _, fold := range classify.Folds(len(fd), *folds) {
+		train, validation := splitFold(fd, fold)
+		weights, _ := splitWeights(w, fold)
+		m, err := f.fit(mi, train, weights)
+		if err != nil {
+			return nil, nil, err
+		}
+		x, y := splitPredict(validation)
+		s, err := f.scores(m, x)
+		if err != nil {
+			return nil, nil, err
+		}
+		for r, i := range fold {
+			scores[i], labels[i] = s[r], y[r]
+		}
+	}
+	return
+}
+
+// outOfFold are the out of fold scores of a model and their labels, or the
+// error that prevented computing them, see outOfFoldScores.
+type outOfFold struct {
+	scores, labels []float64
+	err            error
+}
+
+// outOfFoldScorer computes the out of fold scores of each model on the
+// training data once, so that the calibration and the threshold tuning of
+// the top models share them.
+type outOfFoldScorer struct {
+	dc     data.Container
+	scored map[*ml.ModelContainer]outOfFold
+}
+
+// newOutOfFoldScorer returns an outOfFoldScorer of models trained on dc.
+func newOutOfFoldScorer(dc data.Container) *outOfFoldScorer {
+	return &outOfFoldScorer{dc, make(map[*ml.ModelContainer]outOfFold)}
+}
+
+// scores returns the out of fold scores of the model passed in and their
+// labels, computed on the first call for the model.
+func (o *outOfFoldScorer) scores(mc *ml.ModelContainer) (scores, labels []float64, err error) {
+	r, ok := o.scored[mc]
+	if !ok {
+		r.scores, r.labels, r.err = outOfFoldScores(mc, o.dc)
+		o.scored[mc] = r
+	}
+	return r.scores, r.labels, r.err
+}
+
+// crossCalibrated returns the probabilities of the scores passed in by
+// the calibration fitted on the other folds, so that the probability of a
+// point does not depend on its label.
+func crossCalibrated(calibration string, scores, labels []float64) ([]float64, error) {
+	probabilities := make([]float64, len(scores))
+	for _, fold := range classify.Folds(len(scores), *folds) {
+		in := make(map[int]bool)
+		for _, i := range fold {
+			in[i] = true
+		}
+		var s, y []float64
+		for i := range scores {
+			if !in[i] {
+				s, y = append(s, scores[i]), append(y, labels[i])
+			}
+		}
+		c, err := fitCalibrator(calibration, s, y)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range fold {
+			probabilities[i] = c.probability(scores[i])
+		}
+	}
+	return probabilities, nil
+}
+
+// reliability is the calibration table of the probabilities of a model:
+// the training points are grouped by their predicted probability in bins
+// of the same width and the mean probability of each bin is compared to
+// the fraction of its points labeled +1.
+type reliability struct {
+	Name        string  // name of the model.
+	Calibration string  // calibration of the scores, none for probabilistic models.
+	Brier       float64 // mean squared difference between the probabilities and the labels 0 or 1.
+	Bins        []reliabilityBin
+}
+
+// reliabilityBin is a bin of a reliability table.
+type reliabilityBin struct {
+	From, To  float64 // range of the probabilities of the bin.
+	Count     int     // number of points of the bin.
+	Predicted float64 // mean probability of the points of the bin.
+	Observed  float64 // fraction of the points of the bin labeled +1.
+}
+
+// newReliability returns the reliability table of the probabilities passed
+// in with the number of bins passed in.
+func newReliability(name, calibration string, probabilities, labels []float64, bins int) reliability {
+	r := reliability{Name: name, Calibration: calibration, Bins: make([]reliabilityBin, bins)}
+	for b := range r.Bins {
+		r.Bins[b].From = float64(b) / float64(bins)
+		r.Bins[b].To = float64(b+1) / float64(bins)
+	}
+	for i, p := range probabilities {
+		y := 0.0
+		if labels[i] > 0 {
+			y = 1
+		}
+		r.Brier += (p - y) * (p - y)
+		b := int(p * float64(bins))
+		if b >= bins {
+			b = bins - 1
+		}
+		r.Bins[b].Count++
+		r.Bins[b].Predicted += p
+		r.Bins[b].Observed += y
+	}
+	if len(probabilities) > 0 {
+		r.Brier /= float64(len(probabilities))
+	}
+	for b := range r.Bins {
+		if n := float64(r.Bins[b].Count); n > 0 {
+			r.Bins[b].Predicted /= n
+			r.Bins[b].Observed /= n
+		}
+	}
+	return r
+}
+
+// reliabilities returns the reliability tables of the out of fold
+// probabilities of the models passed in: the probabilities of
+// probabilistic models as they are and the probabilities of every
+// calibration of the calibrate flag.
+//
+func reliabilities(models ml.ModelContainers, oof *outOfFoldScorer, calibrations []string) (tables []reliability) {
+	for _, mc := range models {
+		f := familyOfModel(mc.Model)
+		if f == nil {
+			continue
+		}
+		scores, labels, err := oof.scores(mc)
+		if err != nil {
+			log.Printf("unable to compute the out of fold scores of model %v, %v", mc.Name, err)
+			continue
+		}
+		if f.probabilistic {
+			tables = append(tables, newReliability(mc.Name, "none", scores, labels, *calibrationBins))
+		}
+		for _, c := range calibrations {
+			probabilities, err := crossCalibrated(c, scores, labels)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			tables = append(tables, newReliability(mc.Name, c, probabilities, labels, *calibrationBins))
+		}
+	}
+	return
+}
+
+// calibrateModels writes the reliability tables of the models passed in,
+// the top ranked models, to the file name in the temp folder when the
+// calibrate flag is set. Their out of fold scores are computed by oof.
+//
+func calibrateModels(models ml.ModelContainers, oof *outOfFoldScorer, name string) {
+	if *calibrations == "" {
+		return
+	}
+	cs, err := stringList(*calibrations, plattScaling, isotonicRegression)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if *calibrationBins < 1 {
+		log.Printf("invalid number of calibration bins %v", *calibrationBins)
+		return
+	}
+	writeReliabilities(reliabilities(models, oof, cs), name)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFitPlatt(t *testing.T) {
+	// the higher the score the more likely the label +1, with some noise.
+	scores := []float64{-3, -2, -1.5, -1, -0.5, 0.5, 1, 1.5, 2, 3}
+	labels := []float64{-1, -1, -1, 1, -1, 1, -1, 1, 1, 1}
+	p := fitPlatt(scores, labels)
+	if p.A >= 0 {
+		t.Errorf("A = %v, want a negative slope", p.A)
+	}
+	if q := p.probability(0); math.Abs(q-0.5) > 1e-3 {
+		t.Errorf("probability(0) = %v, want 0.5 for symmetric scores and labels", q)
+	}
+	previous := 0.0
+	for _, s := range []float64{-5, -1, 0, 1, 5} {
+		q := p.probability(s)
+		if q <= previous || q >= 1 {
+			t.Errorf("probability(%v) = %v, want increasing probabilities in (0, 1)", s, q)
+		}
+		previous = q
+	}
+}
+
+func TestFitPlattSeparable(t *testing.T) {
+	// the smoothed targets keep the scaling finite on separable scores.
+	p := fitPlatt([]float64{-2, -1, 1, 2}, []float64{-1, -1, 1, 1})
+	if math.IsNaN(p.A) || math.IsInf(p.A, 0) || math.IsNaN(p.B) || math.IsInf(p.B, 0) {
+		t.Fatalf("fitPlatt = %+v, want finite parameters", p)
+	}
+	if q := p.probability(2); q >= 1 || q < 0.5 {
+		t.Errorf("probability(2) = %v, want a probability in [0.5, 1)", q)
+	}
+}
+
+func TestFitIsotonic(t *testing.T) {
+	tests := []struct {
+		scores, labels []float64
+		want           isotonic
+	}{
+		{
+			[]float64{1, 2, 3, 4}, []float64{-1, 1, -1, 1},
+			isotonic{[]float64{1, 3, 4}, []float64{0, 0.5, 1}},
+		},
+		{
+			// points of the same score are in the same step.
+			[]float64{1, 1, 2}, []float64{1, -1, 1},
+			isotonic{[]float64{1, 2}, []float64{0.5, 1}},
+		},
+		{
+			// decreasing labels are pooled in a single step.
+			[]float64{3, 1, 2}, []float64{-1, 1, 1},
+			isotonic{[]float64{3}, []float64{2.0 / 3}},
+		},
+	}
+	for _, tt := range tests {
+		if got := fitIsotonic(tt.scores, tt.labels); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fitIsotonic(%v, %v) = %+v, want %+v", tt.scores, tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestIsotonicProbability(t *testing.T) {
+	c := isotonic{[]float64{1, 3, 4}, []float64{0, 0.5, 1}}
+	tests := []struct {
+		score, want float64
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0.5},
+		{3.5, 1},
+		{10, 1},
+	}
+	for _, tt := range tests {
+		if got := c.probability(tt.score); got != tt.want {
+			t.Errorf("probability(%v) = %v, want %v", tt.score, got, tt.want)
+		}
+	}
+	if got := (isotonic{}).probability(1); got != 0.5 {
+		t.Errorf("probability of an empty isotonic regression = %v, want 0.5", got)
+	}
+}
+
+func TestOutOfFoldScorerComputesOnce(t *testing.T) {
+	dc := smallContainer()
+	mc, _ := trainedTree(t, dc)
+	oof := newOutOfFoldScorer(dc)
+
+	scores, labels, err := oof.scores(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != len(dc.Data) || len(labels) != len(dc.Data) {
+		t.Fatalf("out of fold scores of %v points and %v labels, want %v", len(scores), len(labels), len(dc.Data))
+	}
+	again, _, _ := oof.scores(mc)
+	if &again[0] != &scores[0] {
+		t.Error("the out of fold scores of a model were computed twice")
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// checkpointEntry describes a trained model: its configuration and its
+// learned state.
+// The errors of a restored model are computed again when it is ranked,
+// so they are not saved.
+type checkpointEntry struct {
+	Key   string          // the configuration the model was trained for.
+	Name  string          // the name of the model.
+	Info  modelInfo       // the description of the model.
+	State json.RawMessage // the learned state: the weights of linear models, the nodes of trees...
+}
+
+// checkpointer periodically writes the models trained so far to a
+// checkpoint file and restores the models of a previous run.
+type checkpointer struct {
+	sync.Mutex
+	path      string
+	data      string                     // hash of the training data, see dataHash.
+	every     int                        // number of models between two writes.
+	completed map[string]checkpointEntry // models of previous runs by key.
+	pending   []checkpointEntry          // models not written yet.
+}
+
+// checkpoints is the checkpointer of the current run, see startCheckpoints.
+var checkpoints *checkpointer
+
+// startCheckpoints sets up the checkpointer of the current run on the
+// training data passed in.
+// If resume is set, the models of the checkpoint file are loaded so
+// that their configurations are not trained again, else the checkpoint
+// file is truncated.
+func startCheckpoints(dc data.Container) {
+	createTempFolder(*tempPath)
+	checkpoints = &checkpointer{
+		path:      *tempPath + "checkpoint.jsonl",
+		data:      dataHash(dc),
+		every:     *checkpointEvery,
+		completed: make(map[string]checkpointEntry),
+	}
+	if !*resume {
+		if err := os.Remove(checkpoints.path); err != nil && !os.IsNotExist(err) {
+			log.Println(err)
+		}
+		return
+	}
+	if err := checkpoints.load(); err != nil {
+		log.Println(err)
+	}
+	if *verbose {
+		fmt.Printf("resuming %v models from %v\n", len(checkpoints.completed), checkpoints.path)
+	}
+}
+
+// load reads the entries of the checkpoint file.
+func (c *checkpointer) load() error {
+	file, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var e checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// the last line may be incomplete if the run crashed while writing it.
+			log.Printf("skipping checkpoint entry, %v", err)
+			continue
+		}
+		c.completed[e.Key] = e
+	}
+	return scanner.Err()
+}
+
+// newCheckpointEntry returns the entry of the model trained for the key passed in.
+func newCheckpointEntry(key string, mc *ml.ModelContainer) (checkpointEntry, error) {
+	e := checkpointEntry{
+		Key:  key,
+		Name: mc.Name,
+		Info: ModelInfoFromModel(mc),
+	}
+	f := familyOfModel(mc.Model)
+	if f == nil {
+		return e, fmt.Errorf("unknown model type for %v", mc.Name)
+	}
+	var err error
+	if e.State, err = f.marshal(mc.Model); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// enabled returns true if the models are checkpointed.
+func (c *checkpointer) enabled() bool {
+	return c != nil && c.every > 0
+}
+
+// save records the entry of a trained model and writes the pending models
+// to the checkpoint file every 'every' models.
+func (c *checkpointer) save(e checkpointEntry) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.pending = append(c.pending, e)
+	if len(c.pending) >= c.every {
+		c.write()
+	}
+}
+
+// keep checkpoints the model trained for the key passed in and caches it
+// unless it was read from the cache. The entry of the model is built once
+// for both.
+func keep(key string, mc *ml.ModelContainer, cached bool) {
+	if !checkpoints.enabled() && (cached || cache == nil) {
+		return
+	}
+	e, err := newCheckpointEntry(key, mc)
+	if err != nil {
+		log.Printf("unable to checkpoint model %v, %v", mc.Name, err)
+		return
+	}
+	checkpoints.save(e)
+	if !cached {
+		cache.put(e)
+	}
+}
+
+// flush writes all the pending models to the checkpoint file.
+func (c *checkpointer) flush() {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.write()
+}
+
+// write appends the pending models to the checkpoint file.
+// The caller must hold the lock.
+func (c *checkpointer) write() {
+	if len(c.pending) == 0 {
+		return
+	}
+	file, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("unable to open checkpoint file %v, %v", c.path, err)
+		return
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, e := range c.pending {
+		b, err := json.Marshal(e)
+		if err != nil {
+			log.Printf("unable to marshal checkpoint entry %v, %v", e.Name, err)
+			continue
+		}
+		writer.Write(append(b, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		log.Printf("unable to write checkpoint file %v, %v", c.path, err)
+		return
+	}
+	c.pending = nil
+}
+
+// restore returns the model of a previous run trained for the key passed in.
+func (c *checkpointer) restore(key string, dc data.Container) (*ml.ModelContainer, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	e, ok := c.completed[key]
+	c.Unlock()
+	if !ok {
+		return nil, false
+	}
+	mc, err := e.model(dc)
+	if err != nil {
+		log.Printf("unable to restore model %v, %v", e.Name, err)
+		return nil, false
+	}
+	return mc, true
+}
+
+// model rebuilds the model of the entry on the data container passed in
+// with the checkpointed state, without training it.
+func (e checkpointEntry) model(dc data.Container) (*ml.ModelContainer, error) {
+
+	f := familyOf(e.Info.Model)
+	if f == nil {
+		return nil, fmt.Errorf("unknown model type %v", e.Info.Model)
+	}
+
+	m := f.newModel(e.Info)
+	fd, w := dc.FilterWithPredict(e.Info.Features), rowWeights(dc)
+	if err := f.restore(m, e.Info, fd, w, e.State); err != nil {
+		return nil, err
+	}
+	m = withResample(f, m, e.Info, fd, w)
+
+	mc := ml.NewModelContainer(m, e.Name, e.Info.Features)
+	mc.TransformDimension = int(e.Info.TransformDimension)
+	mc.TransformID = e.Info.TransformID
+	return mc, nil
+}
+
+// key returns the key of the configuration described by the training
+// stage and the model info passed in, trained on the data of the run with
+// the weighting of the weighting flags. Models trained on other data, or
+// with other sample weights, have other keys and are not restored.
+func (c *checkpointer) key(stage string, mi modelInfo) string {
+	var data string
+	if c != nil {
+		data = c.data
+	}
+	return fmt.Sprintf("%v %v %+v", data, stage, weighted(mi))
+}
+
+// dataHash returns a hash of the data, the features and the predict
+// column of the data container passed in.
+func dataHash(dc data.Container) string {
+	h := sha256.New()
+	b := make([]byte, 8)
+	write := func(v uint64) {
+		binary.LittleEndian.PutUint64(b, v)
+		h.Write(b)
+	}
+	write(uint64(dc.Predict))
+	write(uint64(len(dc.Features)))
+	for _, f := range dc.Features {
+		write(uint64(f))
+	}
+	write(uint64(len(dc.Data)))
+	for _, row := range dc.Data {
+		write(uint64(len(row)))
+		for _, v := range row {
+			write(math.Float64bits(v))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// trainOnce returns the model trained for the training stage and the
+// model info passed in.
+// If the configuration was completed in a previous run, the model is restored
+// from the checkpoint; if it was trained before on the same data, the model is
+// read from the cache; else it is trained with the train function, within the
+// time budget of a fit, checkpointed and cached.
+// It returns an error without training if the training was interrupted.
+// Each restored, trained or failed model is recorded as a fit of the progress.
+func trainOnce(stage string, mi modelInfo, dc data.Container, train func(ctx context.Context) (*ml.ModelContainer, error)) (*ml.ModelContainer, error) {
+	if err := trainCtx.Err(); err != nil {
+		return nil, err
+	}
+	key := checkpoints.key(stage, mi)
+	if mc, ok := checkpoints.restore(key, dc); ok {
+		if *verbose {
+			fmt.Printf("\trestored %v from checkpoint\n", mc.Name)
+		}
+		fits.fit(mc, true)
+		return mc, nil
+	}
+	if mc, ok := cache.get(key, dc); ok {
+		if *verbose {
+			fmt.Printf("\tread %v from cache\n", mc.Name)
+		}
+		keep(key, mc, true)
+		fits.fit(mc, true)
+		return mc, nil
+	}
+	mc, err := trainWithinBudget(train)
+	if err == errFitTimeout {
+		timeouts.add(stage, mi)
+	}
+	if err != nil {
+		fits.fit(nil, false)
+		return nil, err
+	}
+	keep(key, mc, false)
+	fits.fit(mc, false)
+	return mc, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// smallContainer returns a data container of two features, the label
+// being +1 when the first feature is above 2.
+func smallContainer() data.Container {
+	var rows [][]float64
+	for i := 0; i < 20; i++ {
+		y := -1.0
+		if i%5 > 2 {
+			y = 1
+		}
+		rows = append(rows, []float64{float64(i % 5), float64(i % 3), y})
+	}
+	return data.Container{Data: rows, Features: []int{0, 1}, Predict: 2}
+}
+
+// trainedTree returns a decision tree trained on dc.
+func trainedTree(t *testing.T, dc data.Container) (*ml.ModelContainer, modelInfo) {
+	f := familyNamed("tree")
+	mi := f.hyperparameters()[0]
+	mi.Features = []int{0, 1}
+	mc, err := f.container(mi, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mc, mi
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.jsonl")
+
+	dc := smallContainer()
+	mc, mi := trainedTree(t, dc)
+	c := &checkpointer{path: path, data: dataHash(dc), every: 1, completed: make(map[string]checkpointEntry)}
+	key := c.key("combination", mi)
+	e, err := newCheckpointEntry(key, mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.save(e)
+
+	// a run that crashed while writing leaves an incomplete last line.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"Key":"combination {Model:`)
+	file.Close()
+
+	restored := &checkpointer{path: path, every: 1, completed: make(map[string]checkpointEntry)}
+	if err := restored.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.completed) != 1 {
+		t.Fatalf("loaded %v entries, want 1", len(restored.completed))
+	}
+	rmc, ok := restored.restore(key, dc)
+	if !ok {
+		t.Fatal("unable to restore the checkpointed model")
+	}
+	if rmc.Name != mc.Name || !reflect.DeepEqual(rmc.Features, mc.Features) {
+		t.Errorf("restored %v %v, want %v %v", rmc.Name, rmc.Features, mc.Name, mc.Features)
+	}
+	want, _ := mc.Model.Predictions(dc.Filter(mc.Features))
+	got, err := rmc.Model.Predictions(dc.Filter(rmc.Features))
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("restored predictions %v, %v, want %v", got, err, want)
+	}
+	if _, ok := restored.restore("unknown", dc); ok {
+		t.Error("restored a model for an unknown key")
+	}
+}
+
+func TestCheckpointKeyData(t *testing.T) {
+	dc := smallContainer()
+	mi := familyNamed("tree").hyperparameters()[0]
+	mi.Features = []int{0, 1}
+	c := &checkpointer{data: dataHash(dc)}
+
+	changed := smallContainer()
+	changed.Data[3][0]++
+	other := &checkpointer{data: dataHash(changed)}
+
+	if c.key("combination", mi) == other.key("combination", mi) {
+		t.Errorf("models trained on other data have the same checkpoint key")
+	}
+	if c.key("combination", mi) != (&checkpointer{data: dataHash(smallContainer())}).key("combination", mi) {
+		t.Errorf("models trained on the same data have different checkpoint keys")
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/santiaago/ml"
+)
+
+// command is a subcommand of the program.
+// Its flags are a subset of the flags defined in main.go, so every
+// subcommand shares the same flag variables, defaults and experiment file.
+type command struct {
+	name  string
+	usage string   // one line description of the command.
+	flags []string // names of the flags the command accepts.
+	run   func(args []string) error
+}
+
+var (
+	dataFlags   = []string{"config", "trainSrc", "classWeight", "sampleWeights", "temp", "v"}
+	trainFlags  = []string{"linreg", "logreg", "svm", "ksvm", "knn", "tree", "forest", "boost", "nb", "pla", "mlp", "bag", "specific", "comb", "select", "trans", "dim", "reg", "regK", "folds", "checkpointEvery", "resume", "quiet", "fitTimeout", "deadline", "cache"}
+	svmFlags    = []string{"svmK", "svmKRange", "svmL", "svmT"}
+	ksvmFlags   = []string{"ksvmKernel", "ksvmC", "ksvmGamma", "ksvmDegree"}
+	knnFlags    = []string{"knnK", "knnDist", "knnWeight"}
+	treeFlags   = []string{"treeDepth", "treeMinLeaf", "treeCriterion"}
+	forestFlags = []string{"forestTrees", "forestFeatures", "forestBootstrap", "forestDepth", "forestMinLeaf", "forestCriterion", "forestSeed"}
+	boostFlags  = []string{"boostRounds", "boostRate", "boostDepth", "boostMinLeaf", "boostSubsample", "boostStop", "boostSeed"}
+	nbFlags     = []string{"nbSmoothing"}
+	plaFlags    = []string{"plaAlgorithm", "plaIterations", "plaSeed"}
+	mlpFlags    = []string{"mlpHidden", "mlpActivation", "mlpL2", "mlpRate", "mlpEpochs", "mlpBatch", "mlpStop", "mlpSeed"}
+	bagFlags    = []string{"bagFamily", "bagSize", "bagSeed"}
+	importFlags = []string{"ipath", "folds", "osvmK", "osvmL", "osvmT", "svmK", "svmL", "svmT"}
+	rankFlags   = []string{"rankEin", "rankEcv", "top", "ensemble", "calibrate", "calibrationBins", "threshold"}
+	exportFlags = []string{"e", "epath"}
+)
+
+// commands returns the list of subcommands of the program.
+func commands() []command {
+	return []command{
+		{
+			"train",
+			"train the models defined by the flags, rank them and optionally test and export them.",
+			concat(dataFlags, trainFlags, svmFlags, ksvmFlags, knnFlags, treeFlags, forestFlags, boostFlags, nbFlags, plaFlags, mlpFlags, bagFlags, rankFlags, exportFlags, []string{"test", "testSrc"}),
+			runTrain,
+		},
+		{
+			"predict",
+			"restore the models exported to ipath and write their predictions of testSrc.",
+			concat(dataFlags, importFlags, []string{"testSrc"}),
+			runPredict,
+		},
+		{
+			"rank",
+			"train or restore the models of ipath and write their rankings.",
+			concat(dataFlags, importFlags, rankFlags, exportFlags),
+			runRank,
+		},
+		{
+			"export",
+			"train the models defined by the flags and export the top ones to epath.",
+			concat(dataFlags, trainFlags, svmFlags, ksvmFlags, knnFlags, treeFlags, forestFlags, boostFlags, nbFlags, plaFlags, mlpFlags, bagFlags, rankFlags, []string{"epath"}),
+			runExport,
+		},
+		{
+			"import",
+			"train or restore the models of ipath, print their errors and optionally export them.",
+			concat(dataFlags, importFlags, exportFlags),
+			runImport,
+		},
+		{
+			"profile",
+			"train the models defined by the flags writing cpu and memory profiles to the temp folder.",
+			concat(dataFlags, trainFlags, svmFlags, ksvmFlags, knnFlags, treeFlags, forestFlags, boostFlags, nbFlags, plaFlags, mlpFlags, bagFlags, []string{"cpuprofile", "memprofile"}),
+			runProfile,
+		},
+		{
+			"cache",
+			"print the statistics of the trained models cache and prune it.",
+			[]string{"config", "temp", "cache", "cacheMaxAge", "cacheMaxSize"},
+			runCache,
+		},
+		{
+			"compare",
+			"train or restore the models of every model file passed as argument and compare their errors.",
+			concat(dataFlags, []string{"osvmK", "osvmL", "osvmT", "svmK", "svmL", "svmT"}),
+			runCompare,
+		},
+	}
+}
+
+// flagSet returns a flag set with the flags of the command.
+// The flags share their value with the flags defined in main.go.
+func (c command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	for _, name := range c.flags {
+		f := flag.Lookup(name)
+		if f == nil {
+			log.Fatalf("unknown flag %v in command %v", name, c.name)
+		}
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %v %v [flags]\n\n%v\n\nflags:\n", os.Args[0], c.name, c.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usage prints the list of subcommands.
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %v <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-8v %v\n", c.name, c.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun '%v <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+// runCommand parses the flags of the subcommand named in args[0] and runs it.
+func runCommand(args []string) error {
+	for _, c := range commands() {
+		if c.name != args[0] {
+			continue
+		}
+		fs := c.flagSet()
+		fs.Parse(args[1:])
+		setup(fs)
+		return c.run(fs.Args())
+	}
+	usage()
+	return fmt.Errorf("unknown command %v", args[0])
+}
+
+// runTrain trains, tests, ranks and exports the models defined by the flags.
+func runTrain(args []string) error {
+	*canImportModels = false
+
+	var models ml.ModelContainers
+	if models = trainModels(); len(models) == 0 {
+		return fmt.Errorf("no models found")
+	}
+
+	testModels(models)
+
+	models = rank(models)
+
+	exportModels(models, *exportPath)
+	return nil
+}
+
+// runPredict restores the models exported to ipath with their learned
+// state and writes their predictions. Models are never trained again:
+// it fails if one of them has no learned state.
+func runPredict(args []string) error {
+	*test = true
+	if err := checkWeighting(); err != nil {
+		return err
+	}
+
+	modelInfos, err := readModelInfos(*importPath)
+	if err != nil {
+		return err
+	}
+	var models ml.ModelContainers
+	for _, mi := range modelInfos {
+		if f := familyOf(mi.Model); f.learned == nil || len(mi.State) == 0 {
+			return fmt.Errorf("model %v of %v has no learned state, export it again with the train or export command", mi.name(), *importPath)
+		}
+		// the bootstrap samples of bags are drawn by weight.
+		if w := weighted(mi); w.ClassWeight != mi.ClassWeight || w.SampleWeights != mi.SampleWeights {
+			return fmt.Errorf("model %v was trained with class weight %q and sample weights %q, predict with the same weighting flags", mi.name(), mi.ClassWeight, mi.SampleWeights)
+		}
+		models = append(models, importedModel(mi))
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("no models found in %v", *importPath)
+	}
+	if restored := updateModels(trainData(), models); len(restored) < len(models) {
+		return fmt.Errorf("unable to restore %v models of %v", len(models)-len(restored), *importPath)
+	}
+	testModels(models)
+	return nil
+}
+
+// runRank trains or restores the imported models and writes their rankings.
+// If no ranking is chosen models are ranked by cross validation error.
+func runRank(args []string) error {
+	*canImportModels = true
+	if !*rankEin && !*rankEcv {
+		*rankEcv = true
+	}
+
+	var models ml.ModelContainers
+	if models = trainModels(); len(models) == 0 {
+		return fmt.Errorf("no models found in %v", *importPath)
+	}
+	models = rank(models)
+	exportModels(models, *exportPath)
+	return nil
+}
+
+// runExport trains and ranks the models defined by the flags and
+// exports the top ones.
+func runExport(args []string) error {
+	*canImportModels = false
+	*canExportModels = true
+
+	var models ml.ModelContainers
+	if models = trainModels(); len(models) == 0 {
+		return fmt.Errorf("no models found")
+	}
+	models = rank(models)
+	exportModels(models, *exportPath)
+	return nil
+}
+
+// runImport trains or restores the imported models, prints their errors
+// and exports them if the export flag is set.
+func runImport(args []string) error {
+	*canImportModels = true
+
+	var models ml.ModelContainers
+	if models = trainModels(); len(models) == 0 {
+		return fmt.Errorf("no models found in %v", *importPath)
+	}
+	for _, m := range models {
+		fmt.Printf("EIn = %f\tEcv = %f\t%v\n", m.Model.Ein(), m.Model.Ecv(), m.Name)
+	}
+	writeTrees(models, "trees.md")
+	exportModels(models, *exportPath)
+	return nil
+}
+
+// runProfile trains the models defined by the flags while writing a
+// cpu profile and a memory profile to the temp folder.
+func runProfile(args []string) error {
+	*canImportModels = false
+
+	createTempFolder(*tempPath)
+
+	cpu, err := os.Create(*tempPath + *cpuProfile)
+	if err != nil {
+		return err
+	}
+	defer cpu.Close()
+
+	if err = pprof.StartCPUProfile(cpu); err != nil {
+		return err
+	}
+	start := time.Now()
+	models := trainModels()
+	pprof.StopCPUProfile()
+
+	fmt.Printf("trained %v models in %v\n", len(models), time.Since(start))
+
+	mem, err := os.Create(*tempPath + *memProfile)
+	if err != nil {
+		return err
+	}
+	defer mem.Close()
+
+	runtime.GC()
+	if err = pprof.WriteHeapProfile(mem); err != nil {
+		return err
+	}
+	fmt.Printf("profiles written to %v and %v\n", *tempPath+*cpuProfile, *tempPath+*memProfile)
+	return nil
+}
+
+// runCompare trains or restores the models of each model file passed in args and
+// writes a comparison.md file with their errors, sorted by cross validation error.
+func runCompare(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("compare needs at least one model file")
+	}
+
+	*canImportModels = true
+
+	type row struct {
+		file string
+		mc   *ml.ModelContainer
+	}
+	var rows []row
+	for _, path := range args {
+		*importPath = path
+		for _, mc := range trainModels() {
+			rows = append(rows, row{path, mc})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].mc.Model.Ecv() < rows[j].mc.Model.Ecv()
+	})
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + "comparison.md")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	lines := []string{"model comparison\n"}
+	for i, r := range rows {
+		lines = append(lines, fmt.Sprintf("%v\t\tEin = %f\tEcv = %f\tfile: %v\tmodel: %v\n", i, r.mc.Model.Ein(), r.mc.Model.Ecv(), r.file, r.mc.Name))
+	}
+	for _, line := range lines {
+		fmt.Print(line)
+		if _, err := writer.WriteString(line); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// runCache prints the number of models and the size of the cache and
+// prunes it with respect to the cacheMaxAge and cacheMaxSize flags.
+func runCache(args []string) error {
+	if *cachePath == "" {
+		return fmt.Errorf("no cache folder")
+	}
+	stats := func() error {
+		files, err := cacheFiles(*cachePath)
+		if err != nil {
+			return err
+		}
+		var size int64
+		for _, f := range files {
+			size += f.size
+		}
+		fmt.Printf("cache %v: %v models, %.2f MB\n", *cachePath, len(files), float64(size)/(1<<20))
+		return nil
+	}
+	if err := stats(); err != nil {
+		return err
+	}
+	if *cacheMaxAge == 0 && *cacheMaxSize == 0 {
+		return nil
+	}
+	removed, freed, err := pruneCache(*cachePath, *cacheMaxAge, *cacheMaxSize<<20)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("pruned %v models, %.2f MB\n", removed, float64(freed)/(1<<20))
+	return stats()
+}
+
+// concat returns the concatenation of the arrays passed in.
+func concat(arrays ...[]string) (c []string) {
+	for _, a := range arrays {
+		c = append(c, a...)
+	}
+	return
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPredictWithoutLearnedState(t *testing.T) {
+	defer func(path string) { *importPath = path }(*importPath)
+	*importPath = "svm.json"
+
+	err := runPredict(nil)
+	if err == nil || !strings.Contains(err.Error(), "no learned state") {
+		t.Errorf("runPredict() with models exported without learned state returned %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/ml"
+)
+
+// learner trains a model on the data passed in, each row weighted by w
+// if w is not nil.
+// The last column of each row of the data is the value to predict.
+type learner func(fd [][]float64, w []float64) (ml.Model, error)
+
+// splitFold returns the rows of fd that are not in the fold passed in and
+// the rows that are in it.
+func splitFold(fd [][]float64, fold []int) (train, validation [][]float64) {
+	in := make(map[int]bool)
+	for _, i := range fold {
+		in[i] = true
+	}
+	for i, row := range fd {
+		if in[i] {
+			validation = append(validation, row)
+		} else {
+			train = append(train, row)
+		}
+	}
+	return
+}
+
+// crossValidationError returns the k-fold cross validation error of
+// the models trained on fd, weighted by w, by the learner passed in.
+// The folds are the ones of classify.Folds, also used by the families of
+// this repository for their own Ecv, so that the errors of every family
+// are comparable.
+// The error is the fraction of misclassified rows over all the folds.
+func crossValidationError(fd [][]float64, w []float64, k int, learn learner) (float64, error) {
+	if len(fd) == 0 {
+		return 0, fmt.Errorf("no data to cross validate")
+	}
+	var wrong int
+	for _, fold := range classify.Folds(len(fd), k) {
+		train, validation := splitFold(fd, fold)
+		weights, _ := splitWeights(w, fold)
+		m, err := learn(train, weights)
+		if err != nil {
+			return 0, err
+		}
+		x, y := splitPredict(validation)
+		predictions, err := m.Predictions(x)
+		if err != nil {
+			return 0, err
+		}
+		for i := range predictions {
+			if predictions[i] != y[i] {
+				wrong++
+			}
+		}
+	}
+	return float64(wrong) / float64(len(fd)), nil
+}
+
+// splitPredict returns the features and the values to predict
+// of the data passed in.
+func splitPredict(fd [][]float64) (x [][]float64, y []float64) {
+	for _, row := range fd {
+		x = append(x, row[:len(row)-1])
+		y = append(y, row[len(row)-1])
+	}
+	return
+}
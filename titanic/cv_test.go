@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+func TestSplitFold(t *testing.T) {
+	fd := [][]float64{{0, 1}, {1, -1}, {2, 1}, {3, -1}, {4, 1}}
+	tests := []struct {
+		fold              []int
+		train, validation [][]float64
+	}{
+		{[]int{0, 3}, [][]float64{{1, -1}, {2, 1}, {4, 1}}, [][]float64{{0, 1}, {3, -1}}},
+		{[]int{4}, [][]float64{{0, 1}, {1, -1}, {2, 1}, {3, -1}}, [][]float64{{4, 1}}},
+		{nil, fd, nil},
+	}
+	for _, tt := range tests {
+		train, validation := splitFold(fd, tt.fold)
+		if !reflect.DeepEqual(train, tt.train) || !reflect.DeepEqual(validation, tt.validation) {
+			t.Errorf("splitFold(%v) = %v, %v, want %v, %v", tt.fold, train, validation, tt.train, tt.validation)
+		}
+	}
+}
+
+// The folds of the cross validation of the titanic models are the ones
+// of the families of this repository, so every row is validated once.
+func TestCrossValidationFolds(t *testing.T) {
+	folds := classify.Folds(7, 3)
+	want := [][]int{{0, 3, 6}, {1, 4}, {2, 5}}
+	if !reflect.DeepEqual(folds, want) {
+		t.Errorf("classify.Folds(7, 3) = %v, want %v", folds, want)
+	}
+	seen := make(map[int]int)
+	for _, fold := range classify.Folds(5, 10) {
+		for _, i := range fold {
+			seen[i]++
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if seen[i] != 1 {
+			t.Errorf("row %v is in %v folds, want 1", i, seen[i])
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// Votes of the ensembles.
+const (
+	hardVote     = "hard"     // every member has one vote.
+	weightedVote = "weighted" // every member votes with a weight of 1 - Ecv.
+	stackedVote  = "stacked"  // a logistic regression learns from the votes, see stacking.
+)
+
+// ensemble is a voting classifier made of trained models. It predicts +1
+// when the weighted sum of the predictions of its members is positive or
+// zero and -1 otherwise.
+// Its data are the columns of the features of all its members, see
+// features, so the members pick their own columns from them.
+type ensemble struct {
+	vote       string             // hardVote or weightedVote.
+	members    ml.ModelContainers // trained members.
+	thresholds []*float64         // decision threshold of each member, see memberThresholds.
+	weights    []float64          // weight of the vote of each member.
+	features   []int              // sorted union of the features of the members.
+	dc         data.Container     // training data of the members.
+	ein, ecv   float64
+}
+
+// newEnsemble returns an untrained ensemble of the members passed in,
+// trained on dc.
+func newEnsemble(vote string, members ml.ModelContainers, dc data.Container) *ensemble {
+	return &ensemble{vote: vote, members: members, thresholds: memberThresholds(members), features: unionFeatures(members), dc: dc}
+}
+
+// Learn sets the weights of the members and computes the in sample and
+// cross validation errors of the ensemble.
+// The cross validation trains every member again on the same folds as
+// the cross validation of the models, see classify.Folds, and keeps
+// the decision thresholds of the members. The thresholds were tuned on
+// all the training points, the validation folds included, so the cross
+// validation error of an ensemble of tuned members is optimistic.
+func (e *ensemble) Learn() error {
+	e.weights = make([]float64, len(e.members))
+	for i, m := range e.members {
+		e.weights[i] = 1
+		if e.vote == weightedVote {
+			e.weights[i] = 1 - m.Model.Ecv()
+		}
+	}
+
+	predictions, err := e.Predictions(e.dc.Filter(e.features))
+	if err != nil {
+		return err
+	}
+	e.ein = wrongPredictions(e.dc.Data, e.dc.Predict, predictions) / float64(len(e.dc.Data))
+
+	var wrong float64
+	for _, fold := range classify.Folds(len(e.dc.Data), *folds) {
+		train, validation := splitContainer(e.dc, fold)
+		members, err := refitMembers(e.members, train)
+		if err != nil {
+			return err
+		}
+		predictions, err := e.votes(members, validation.Filter(e.features))
+		if err != nil {
+			return err
+		}
+		wrong += wrongPredictions(validation.Data, e.dc.Predict, predictions)
+	}
+	e.ecv = wrong / float64(len(e.dc.Data))
+	return nil
+}
+
+// Ein returns the in sample error.
+func (e *ensemble) Ein() float64 {
+	return e.ein
+}
+
+// Ecv returns the cross validation error.
+func (e *ensemble) Ecv() float64 {
+	return e.ecv
+}
+
+// Predictions returns the predictions of the points passed in, without
+// labels, with the columns of the features of the ensemble.
+func (e *ensemble) Predictions(x [][]float64) ([]float64, error) {
+	return e.votes(e.members, x)
+}
+
+// votes returns the weighted votes of the members passed in, the members
+// of the ensemble or the same members trained on a fold, for x.
+func (e *ensemble) votes(members ml.ModelContainers, x [][]float64) ([]float64, error) {
+	sums := make([]float64, len(x))
+	for i, m := range members {
+		predictions, err := memberPredictions(m, e.thresholds[i], e.features, x)
+		if err != nil {
+			return nil, err
+		}
+		for r, p := range predictions {
+			sums[r] += e.weights[i] * p
+		}
+	}
+	for r := range sums {
+		sums[r] = classify.Sign(sums[r])
+	}
+	return sums, nil
+}
+
+// unionFeatures returns the sorted union of the features of the models
+// passed in.
+func unionFeatures(models ml.ModelContainers) (features []int) {
+	seen := make(map[int]bool)
+	for _, m := range models {
+		for _, f := range m.Features {
+			if !seen[f] {
+				seen[f] = true
+				features = append(features, f)
+			}
+		}
+	}
+	sort.Ints(features)
+	return
+}
+
+// memberThresholds returns the decision threshold of each model passed in,
+// see thresholdModels, or nil for the models without one.
+// The members of an ensemble trained again on a fold use the thresholds
+// of the members they were trained from.
+func memberThresholds(models ml.ModelContainers) []*float64 {
+	ts := make([]*float64, len(models))
+	for i, m := range models {
+		if t, ok := thresholds[m]; ok {
+			ts[i] = &t
+		}
+	}
+	return ts
+}
+
+// memberPredictions returns the predictions of the model passed in for x,
+// points with the columns of the features passed in, a superset of the
+// features of the model.
+// If threshold is not nil the model predicts with it, see thresholdPredictions.
+func memberPredictions(m *ml.ModelContainer, threshold *float64, features []int, x [][]float64) ([]float64, error) {
+	columns := make([]int, len(m.Features))
+	for j, f := range m.Features {
+		columns[j] = sort.SearchInts(features, f)
+	}
+	mx := make([][]float64, len(x))
+	for r, row := range x {
+		mx[r] = make([]float64, len(columns))
+		for j, c := range columns {
+			mx[r][j] = row[c]
+		}
+	}
+	predict := func(x [][]float64) ([]float64, error) { return familyOfModel(m.Model).predict(m.Model, x) }
+	if threshold != nil {
+		predict = func(x [][]float64) ([]float64, error) { return thresholdPredictions(m, *threshold, x) }
+	}
+	predictions, err := predict(mx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to predict with model %v, %v", m.Name, err)
+	}
+	return predictions, nil
+}
+
+// splitContainer returns the rows of dc that are not in the fold passed
+// in and the rows that are in it, see splitFold.
+func splitContainer(dc data.Container, fold []int) (train, validation data.Container) {
+	t, v := splitFold(dc.Data, fold)
+	train = data.Container{Data: t, Features: dc.Features, Predict: dc.Predict}
+	validation = data.Container{Data: v, Features: dc.Features, Predict: dc.Predict}
+	return
+}
+
+// refitMembers returns the models passed in trained again on dc with the
+// same hyperparameters.
+func refitMembers(models ml.ModelContainers, dc data.Container) (ml.ModelContainers, error) {
+	refit := make(ml.ModelContainers, len(models))
+	for i, m := range models {
+		model, err := familyOfModel(m.Model).fit(ModelInfoFromModel(m), dc.FilterWithPredict(m.Features), rowWeights(dc))
+		if err != nil {
+			return nil, fmt.Errorf("unable to train model %v again, %v", m.Name, err)
+		}
+		refit[i] = ml.NewModelContainer(model, m.Name, m.Features)
+	}
+	return refit, nil
+}
+
+// wrongPredictions returns the number of rows whose label, the column
+// predict, is not the prediction.
+func wrongPredictions(rows [][]float64, predict int, predictions []float64) (wrong float64) {
+	for i, row := range rows {
+		if predictions[i] != row[predict] {
+			wrong++
+		}
+	}
+	return
+}
+
+// ensembleModels returns a trained ensemble of the models passed in, the
+// top ranked models, for each vote of the ensemble flag.
+// The out of fold predictions of the stacking are written to the temp folder.
+//
+func ensembleModels(models ml.ModelContainers) (ensembles ml.ModelContainers) {
+	if *ensembleVotes == "" {
+		return
+	}
+	votes, err := stringList(*ensembleVotes, hardVote, weightedVote, stackedVote)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	var members ml.ModelContainers
+	for _, m := range models {
+		if m != nil && familyOfModel(m.Model) != nil {
+			members = append(members, m)
+		}
+	}
+	if len(members) < 2 {
+		log.Printf("not enough models to make an ensemble, %v", len(members))
+		return
+	}
+
+	dc, err := NewPassengerReader(*trainSrc, NewPassengerTrainExtractor()).Read()
+	if err != nil {
+		log.Println("error when getting the data.container from the reader,", err)
+		return
+	}
+
+	for _, v := range votes {
+		if *verbose {
+			fmt.Printf("training %v vote ensemble of %v models\n", v, len(members))
+		}
+		var e ml.Model = newEnsemble(v, members, dc)
+		name := fmt.Sprintf("ensemble %v vote of %v models", v, len(members))
+		if v == stackedVote {
+			e = newStacking(members, dc)
+			name = fmt.Sprintf("ensemble stacking of %v models", len(members))
+		}
+		if err := e.Learn(); err != nil {
+			log.Printf("unable to train %v vote ensemble, %v", v, err)
+			continue
+		}
+		if s, ok := e.(*stacking); ok {
+			if err := writeOutOfFold(s, "stacking.oof.csv"); err != nil {
+				log.Println(err)
+			}
+		}
+		ensembles = append(ensembles, ml.NewModelContainer(e, name, unionFeatures(members)))
+	}
+	return
+}
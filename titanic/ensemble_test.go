@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/santiaago/ml"
+)
+
+func TestEnsembleKeepsThresholds(t *testing.T) {
+	dc := smallContainer()
+	tuned, _ := trainedTree(t, dc)
+	other, _ := trainedTree(t, dc)
+	thresholds[tuned] = 0.3
+	defer delete(thresholds, tuned)
+
+	n := len(thresholds)
+	e := newEnsemble(hardVote, ml.ModelContainers{tuned, other}, dc)
+	if err := e.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if len(thresholds) != n {
+		t.Errorf("training an ensemble added %v decision thresholds", len(thresholds)-n)
+	}
+	if e.thresholds[0] == nil || *e.thresholds[0] != 0.3 || e.thresholds[1] != nil {
+		t.Errorf("ensemble member thresholds = %v, want [0.3 none]", e.thresholds)
+	}
+}
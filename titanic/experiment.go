@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// experiment describes a complete run of the program: data sources,
+// preprocessing, model families, search strategy, hyperparameters,
+// ranking criteria and outputs.
+// Every field is bound to the flag of the same meaning, see bindings.
+type experiment struct {
+	Data struct {
+		Train         string // training set.
+		Test          string // testing set.
+		ClassWeight   string // weights of the classes when training: balanced or 'died,survived'.
+		SampleWeights string // csv file of the weights of the training passengers.
+	}
+	Preprocessing struct {
+		Transforms bool // train models with transformations.
+		Dimension  int  // dimension of transformation.
+	}
+	Models struct {
+		Linreg bool // train linear regressions.
+		Logreg bool // train logistic regressions.
+		Svm    bool // train support vector machines.
+		Ksvm   bool // train kernel support vector machines.
+		Knn    bool // train k-nearest-neighbours classifiers.
+		Tree   bool // train decision trees.
+		Forest bool // train random forests.
+		Boost  bool // train gradient boosted trees.
+		Nb     bool // train naive Bayes classifiers.
+		Pla    bool // train perceptrons.
+		Mlp    bool // train multilayer perceptrons.
+		Bag    bool // train bags of models of other families.
+	}
+	Search struct {
+		Specific     bool   // train specific models.
+		Combinations string // feature combination sizes: 'n', 'from:to' or 'all'.
+		Selection    string // stepwise feature selection: forward, backward or floating.
+		Regularized  bool   // train models with regularization.
+		Folds        int    // number of folds used for cross validation.
+		Checkpoint   int    // number of trained models between two checkpoint writes.
+		Resume       bool   // resume from the checkpoint file.
+		FitTimeout   string // time budget of a single model fit.
+		Deadline     string // time budget of the whole training.
+		Cache        string // folder of the trained models cache.
+	}
+	Hyperparameters struct {
+		RegK            string  // range of k values to try when regularizing.
+		SvmK            int     // block size of the svm pegasos algorithm.
+		SvmKRange       int     // range of block sizes of the svm pegasos algorithm.
+		SvmLambda       float64 // svm regularization parameter.
+		SvmT            int     // number of iterations of the svm pegasos algorithm.
+		KsvmKernel      string  // kernels of the kernel svms.
+		KsvmC           string  // penalties of the margin violations of the kernel svms.
+		KsvmGamma       string  // scales of the kernels of the kernel svms.
+		KsvmDegree      string  // degrees of the polynomial kernels of the kernel svms.
+		KnnK            string  // numbers of neighbours of the knn classifiers.
+		KnnDistance     string  // distances of the knn classifiers.
+		KnnWeighting    string  // weightings of the votes of the knn classifiers.
+		TreeDepth       string  // maximum depths of the decision trees.
+		TreeMinLeaf     string  // minimum numbers of training points in a leaf of the decision trees.
+		TreeCriterion   string  // impurity criteria of the decision trees.
+		ForestTrees     string  // numbers of trees of the random forests.
+		ForestFeatures  string  // numbers of features tried at each split of the random forests.
+		ForestBootstrap bool    // grow the trees of the random forests on bootstrap samples.
+		ForestDepth     string  // maximum depths of the trees of the random forests.
+		ForestMinLeaf   string  // minimum numbers of training points in a leaf of the random forests.
+		ForestCriterion string  // impurity criteria of the random forests.
+		ForestSeed      int     // seed of the random forests.
+		BoostRounds     string  // maximum numbers of trees of the gradient boosting.
+		BoostRate       string  // learning rates of the gradient boosting.
+		BoostDepth      string  // maximum depths of the trees of the gradient boosting.
+		BoostMinLeaf    int     // minimum number of training points in a leaf of the gradient boosting.
+		BoostSubsample  string  // fractions of the training points used to fit each tree of the gradient boosting.
+		BoostStop       int     // rounds without improvement of the validation loss before stopping the gradient boosting.
+		BoostSeed       int     // seed of the subsamples of the gradient boosting.
+		NbSmoothing     string  // additive smoothings of the naive Bayes classifiers.
+		PlaAlgorithm    string  // algorithms of the perceptrons: pla or pocket.
+		PlaIterations   string  // maximum numbers of iterations of the perceptrons.
+		PlaSeed         int     // seed of the perceptrons.
+		MlpHidden       string  // widths of the hidden layers of the multilayer perceptrons.
+		MlpActivation   string  // activations of the hidden units of the multilayer perceptrons.
+		MlpL2           string  // L2 penalties of the weights of the multilayer perceptrons.
+		MlpRate         string  // learning rates of the multilayer perceptrons.
+		MlpEpochs       string  // maximum numbers of epochs of the multilayer perceptrons.
+		MlpBatch        int     // number of training points of each mini-batch of the multilayer perceptrons.
+		MlpStop         int     // epochs without improvement of the validation loss before stopping the multilayer perceptrons.
+		MlpSeed         int     // seed of the multilayer perceptrons.
+		BagFamily       string  // families of the bagged models.
+		BagSize         string  // numbers of bootstrap samples of the bags.
+		BagSeed         int     // seed of the bootstrap samples of the bags.
+		SvmKOverride    bool    // override svmK of imported models.
+		SvmLOverride    bool    // override svmL of imported models.
+		SvmTOverride    bool    // override svmT of imported models.
+	}
+	Ranking struct {
+		Ein             bool   // rank models by in sample error.
+		Ecv             bool   // rank models by cross validation error.
+		Top             int    // number of models to keep.
+		Ensemble        string // votes of the ensembles of the top models: hard, weighted or stacked.
+		Calibrate       string // calibrations of the scores of the top models: platt or isotonic.
+		CalibrationBins int    // number of bins of the reliability tables.
+		Threshold       string // metric maximized by the decision thresholds of the top models.
+	}
+	Output struct {
+		Temp       string // folder where model results and rankings are written.
+		Test       bool   // write the predictions of the test set.
+		Export     bool   // export the used models.
+		ExportPath string // path of the exported models.
+		Import     bool   // import the models defined in ImportPath.
+		ImportPath string // path of the imported models.
+		Verbose    bool   // print additional output.
+		Quiet      bool   // print a line per training stage instead of the progress line.
+	}
+}
+
+// bindings returns a map from flag names to the experiment field
+// that holds the value of the flag.
+func (e *experiment) bindings() map[string]interface{} {
+	return map[string]interface{}{
+		"trainSrc":        &e.Data.Train,
+		"testSrc":         &e.Data.Test,
+		"classWeight":     &e.Data.ClassWeight,
+		"sampleWeights":   &e.Data.SampleWeights,
+		"trans":           &e.Preprocessing.Transforms,
+		"dim":             &e.Preprocessing.Dimension,
+		"linreg":          &e.Models.Linreg,
+		"logreg":          &e.Models.Logreg,
+		"svm":             &e.Models.Svm,
+		"ksvm":            &e.Models.Ksvm,
+		"knn":             &e.Models.Knn,
+		"tree":            &e.Models.Tree,
+		"forest":          &e.Models.Forest,
+		"boost":           &e.Models.Boost,
+		"nb":              &e.Models.Nb,
+		"pla":             &e.Models.Pla,
+		"mlp":             &e.Models.Mlp,
+		"bag":             &e.Models.Bag,
+		"specific":        &e.Search.Specific,
+		"comb":            &e.Search.Combinations,
+		"select":          &e.Search.Selection,
+		"reg":             &e.Search.Regularized,
+		"folds":           &e.Search.Folds,
+		"checkpointEvery": &e.Search.Checkpoint,
+		"resume":          &e.Search.Resume,
+		"fitTimeout":      &e.Search.FitTimeout,
+		"deadline":        &e.Search.Deadline,
+		"cache":           &e.Search.Cache,
+		"regK":            &e.Hyperparameters.RegK,
+		"svmK":            &e.Hyperparameters.SvmK,
+		"svmKRange":       &e.Hyperparameters.SvmKRange,
+		"svmL":            &e.Hyperparameters.SvmLambda,
+		"svmT":            &e.Hyperparameters.SvmT,
+		"ksvmKernel":      &e.Hyperparameters.KsvmKernel,
+		"ksvmC":           &e.Hyperparameters.KsvmC,
+		"ksvmGamma":       &e.Hyperparameters.KsvmGamma,
+		"ksvmDegree":      &e.Hyperparameters.KsvmDegree,
+		"knnK":            &e.Hyperparameters.KnnK,
+		"knnDist":         &e.Hyperparameters.KnnDistance,
+		"knnWeight":       &e.Hyperparameters.KnnWeighting,
+		"treeDepth":       &e.Hyperparameters.TreeDepth,
+		"treeMinLeaf":     &e.Hyperparameters.TreeMinLeaf,
+		"treeCriterion":   &e.Hyperparameters.TreeCriterion,
+		"forestTrees":     &e.Hyperparameters.ForestTrees,
+		"forestFeatures":  &e.Hyperparameters.ForestFeatures,
+		"forestBootstrap": &e.Hyperparameters.ForestBootstrap,
+		"forestDepth":     &e.Hyperparameters.ForestDepth,
+		"forestMinLeaf":   &e.Hyperparameters.ForestMinLeaf,
+		"forestCriterion": &e.Hyperparameters.ForestCriterion,
+		"forestSeed":      &e.Hyperparameters.ForestSeed,
+		"boostRounds":     &e.Hyperparameters.BoostRounds,
+		"boostRate":       &e.Hyperparameters.BoostRate,
+		"boostDepth":      &e.Hyperparameters.BoostDepth,
+		"boostMinLeaf":    &e.Hyperparameters.BoostMinLeaf,
+		"boostSubsample":  &e.Hyperparameters.BoostSubsample,
+		"boostStop":       &e.Hyperparameters.BoostStop,
+		"boostSeed":       &e.Hyperparameters.BoostSeed,
+		"nbSmoothing":     &e.Hyperparameters.NbSmoothing,
+		"plaAlgorithm":    &e.Hyperparameters.PlaAlgorithm,
+		"plaIterations":   &e.Hyperparameters.PlaIterations,
+		"plaSeed":         &e.Hyperparameters.PlaSeed,
+		"mlpHidden":       &e.Hyperparameters.MlpHidden,
+		"mlpActivation":   &e.Hyperparameters.MlpActivation,
+		"mlpL2":           &e.Hyperparameters.MlpL2,
+		"mlpRate":         &e.Hyperparameters.MlpRate,
+		"mlpEpochs":       &e.Hyperparameters.MlpEpochs,
+		"mlpBatch":        &e.Hyperparameters.MlpBatch,
+		"mlpStop":         &e.Hyperparameters.MlpStop,
+		"mlpSeed":         &e.Hyperparameters.MlpSeed,
+		"bagFamily":       &e.Hyperparameters.BagFamily,
+		"bagSize":         &e.Hyperparameters.BagSize,
+		"bagSeed":         &e.Hyperparameters.BagSeed,
+		"osvmK":           &e.Hyperparameters.SvmKOverride,
+		"osvmL":           &e.Hyperparameters.SvmLOverride,
+		"osvmT":           &e.Hyperparameters.SvmTOverride,
+		"rankEin":         &e.Ranking.Ein,
+		"rankEcv":         &e.Ranking.Ecv,
+		"top":             &e.Ranking.Top,
+		"ensemble":        &e.Ranking.Ensemble,
+		"calibrate":       &e.Ranking.Calibrate,
+		"calibrationBins": &e.Ranking.CalibrationBins,
+		"threshold":       &e.Ranking.Threshold,
+		"temp":            &e.Output.Temp,
+		"test":            &e.Output.Test,
+		"e":               &e.Output.Export,
+		"epath":           &e.Output.ExportPath,
+		"i":               &e.Output.Import,
+		"ipath":           &e.Output.ImportPath,
+		"v":               &e.Output.Verbose,
+		"quiet":           &e.Output.Quiet,
+	}
+}
+
+// experimentFromFlags returns the experiment described by the current
+// values of the flags.
+func experimentFromFlags() (e experiment) {
+	for name, field := range e.bindings() {
+		f := flag.Lookup(name)
+		if f == nil {
+			continue
+		}
+		g, ok := f.Value.(flag.Getter)
+		if !ok {
+			continue
+		}
+		switch v := field.(type) {
+		case *string:
+			// durations are stored as strings such as 1m30s.
+			*v = fmt.Sprint(g.Get())
+		case *bool:
+			*v = g.Get().(bool)
+		case *int:
+			*v = g.Get().(int)
+		case *float64:
+			*v = g.Get().(float64)
+		}
+	}
+	return
+}
+
+// loadExperiment reads the experiment file at path and sets the flags
+// with the values of the experiment.
+// Flags set on the command line, that is parsed by the flag set passed in,
+// override the values of the experiment.
+// Fields missing from the file keep the value of their flag.
+func loadExperiment(fs *flag.FlagSet, path string) error {
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read experiment file %v, %v", path, err)
+	}
+
+	e := experimentFromFlags()
+	if err = json.Unmarshal(b, &e); err != nil {
+		return fmt.Errorf("unable to unmarshal experiment file %v, %v", path, err)
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for name, field := range e.bindings() {
+		if explicit[name] || flag.Lookup(name) == nil {
+			continue
+		}
+		var value string
+		switch v := field.(type) {
+		case *string:
+			value = *v
+		case *bool:
+			value = strconv.FormatBool(*v)
+		case *int:
+			value = strconv.Itoa(*v)
+		case *float64:
+			value = strconv.FormatFloat(*v, 'g', -1, 64)
+		}
+		if err = flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %v for %v in experiment file %v, %v", value, name, path, err)
+		}
+	}
+	return nil
+}
+
+// writeExperiment writes the experiment described by the current values
+// of the flags to the file name in the temp folder, so that the run can be
+// repeated with the config flag.
+func writeExperiment(name string) error {
+
+	createTempFolder(*tempPath)
+
+	b, err := json.MarshalIndent(experimentFromFlags(), "", "    ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal experiment, %v", err)
+	}
+	if err = ioutil.WriteFile(*tempPath+name, b, 0644); err != nil {
+		return fmt.Errorf("unable to write experiment to file %v, %v", *tempPath+name, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setFlags sets the flags passed in by name and returns a function that
+// sets them back to their values.
+func setFlags(t *testing.T, values map[string]string) (restore func()) {
+	old := make(map[string]string)
+	for name, value := range values {
+		old[name] = flag.Lookup(name).Value.String()
+		if err := flag.Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for name, value := range old {
+			flag.Set(name, value)
+		}
+	}
+}
+
+func TestLoadExperimentPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "experiment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "experiment.json")
+	config := `{
+		"Models": {"Svm": true},
+		"Search": {"Combinations": "2:3", "Folds": 5},
+		"Ranking": {"Top": 7}
+	}`
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setFlags(t, map[string]string{"svm": "false", "comb": "", "folds": "10", "top": "10", "regK": "-5:5"})()
+
+	// folds is set on the command line, so it overrides the file.
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+	fs.Int("folds", 10, "")
+	if err := fs.Parse([]string{"-folds=3"}); err != nil {
+		t.Fatal(err)
+	}
+	flag.Set("folds", "3")
+
+	if err := loadExperiment(fs, path); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, want string
+	}{
+		{"svm", "true"},     // from the file.
+		{"comb", "2:3"},     // from the file.
+		{"top", "7"},        // from the file.
+		{"folds", "3"},      // from the command line.
+		{"regK", "-5:5"},    // missing from the file, keeps its flag value.
+		{"linreg", "false"}, // missing from the file, keeps its flag value.
+	}
+	for _, tt := range tests {
+		if got := flag.Lookup(tt.name).Value.String(); got != tt.want {
+			t.Errorf("flag %v = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadExperimentErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "experiment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fs := flag.NewFlagSet("train", flag.ContinueOnError)
+
+	if err := loadExperiment(fs, filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing experiment file")
+	}
+	path := filepath.Join(dir, "invalid.json")
+	ioutil.WriteFile(path, []byte(`{"Search": {"Folds": "five"}}`), 0644)
+	if err := loadExperiment(fs, path); err == nil {
+		t.Error("expected an error for an invalid experiment file")
+	}
+}
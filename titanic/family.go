@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// modelFamily describes a family of models, linear regressions for example.
+// Each family registers itself once with registerFamily and every stage of the
+// pipeline, from training to testing, checkpointing and exporting, iterates
+// over the registered families.
+type modelFamily struct {
+	model ModelType // the type of the family in model files.
+	name  string    // short name used in model names and training stages.
+
+	// enabled is the flag that defines if the family is trained.
+	enabled *bool
+
+	// regularizable is true if the models of the family can be regularized
+	// with lambda = 10^-k, see learn.
+	regularizable bool
+
+	// params is the schema of the hyperparameters of the family stored in
+	// the Params of a model info, in the order they appear in model names.
+	params []hyperparameter
+
+	// is returns true if the model passed in belongs to the family.
+	is func(m ml.Model) bool
+
+	// hyperparameters returns a model info, without features, for each
+	// hyperparameter setting to train on every feature set.
+	// The first one is the default setting of the family.
+	hyperparameters func() []modelInfo
+
+	// newModel returns an untrained model described by the model info,
+	// with its transform function and hyperparameters set.
+	newModel func(mi modelInfo) ml.Model
+
+	// initialize sets the training data of the model and transforms it
+	// if the model has a transform function.
+	initialize func(m ml.Model, fd [][]float64) error
+
+	// weigh sets the weights of the training points of an initialized
+	// model, in the order of the rows passed to initialize.
+	weigh func(m ml.Model, w []float64) error
+
+	// resampled is true if the models of the family have no weights: weigh
+	// replaces their training points by a resample of them by weight, see
+	// resampleRows, so they are not weighted fits. Their weighted models
+	// are wrapped in a resampledModel, which computes their errors on the
+	// original training points, and the family functions unwrap them.
+	resampled bool
+
+	// learn trains an initialized model, regularized if the model info is.
+	learn func(m ml.Model, mi modelInfo) error
+
+	// predict returns the predictions of the model for the data passed in.
+	predict func(m ml.Model, x [][]float64) ([]float64, error)
+
+	// scores returns a score of each point of x, a higher score meaning a
+	// higher probability of the label +1. The scores of probabilistic
+	// families are probabilities, the others are margins mapped to
+	// probabilities by a calibration, see calibrate.go.
+	scores        func(m ml.Model, x [][]float64) ([]float64, error)
+	probabilistic bool
+
+	// describe sets the hyperparameters of the model in the model info.
+	describe func(m ml.Model, mi *modelInfo)
+
+	// marshal returns the learned state of the model and unmarshal sets it
+	// back on an initialized model.
+	marshal   func(m ml.Model) (json.RawMessage, error)
+	unmarshal func(m ml.Model, mi modelInfo, state json.RawMessage) error
+
+	// label returns the hyperparameters of the model info that are not
+	// in Params as they appear in model names. It can be nil.
+	label func(mi modelInfo) string
+
+	// learned returns true if the model holds a learned state. It can be nil.
+	// The learned state of the models of the families that set it is
+	// exported with their model info and imported models with a state are
+	// restored instead of being trained again.
+	learned func(m ml.Model) bool
+
+	// override applies the override flags to the model info of an
+	// imported model. It can be nil.
+	override func(mi modelInfo) modelInfo
+
+	// wrapped returns the family of the models wrapped by the model info,
+	// like the members of a bag, whose hyperparameters are valid in the
+	// Params of the model info. It can be nil.
+	wrapped func(mi modelInfo) *modelFamily
+}
+
+// hyperparameter describes a hyperparameter of a model family.
+type hyperparameter struct {
+	name  string // key in the Params of a model info, also used in model names.
+	usage string // description of the hyperparameter.
+}
+
+// families holds the registered model families ordered by model type.
+var families []*modelFamily
+
+// registerFamily adds the model family passed in to the registry.
+// It is called from the init function of the file of each family.
+func registerFamily(f *modelFamily) {
+	for _, g := range families {
+		if g.model == f.model || g.name == f.name {
+			panic(fmt.Sprintf("model family %v registered twice", f.name))
+		}
+	}
+	families = append(families, f)
+	sort.Slice(families, func(i, j int) bool { return families[i].model < families[j].model })
+}
+
+// familyOf returns the family of the model type passed in or nil if the
+// type is not registered.
+func familyOf(t ModelType) *modelFamily {
+	for _, f := range families {
+		if f.model == t {
+			return f
+		}
+	}
+	return nil
+}
+
+// familyNamed returns the family of the name passed in or nil if no
+// family has this name.
+func familyNamed(name string) *modelFamily {
+	for _, f := range families {
+		if f.name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// familyOfModel returns the family of the model passed in or nil if the
+// model does not belong to a registered family.
+func familyOfModel(m ml.Model) *modelFamily {
+	for _, f := range families {
+		if f.is(m) {
+			return f
+		}
+	}
+	return nil
+}
+
+// fit returns a model described by the model info and trained on fd,
+// each row weighted by w if w is not nil.
+func (f *modelFamily) fit(mi modelInfo, fd [][]float64, w []float64) (ml.Model, error) {
+	m := f.newModel(mi)
+	if err := f.initialize(m, fd); err != nil {
+		return nil, err
+	}
+	if w != nil {
+		if err := f.weigh(m, w); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.learn(m, mi); err != nil {
+		return nil, err
+	}
+	return withResample(f, m, mi, fd, w), nil
+}
+
+// restore sets the learned state passed in on the model described by the
+// model info, initialized on fd and weighted by w if w is not nil as in
+// fit, without training it.
+func (f *modelFamily) restore(m ml.Model, mi modelInfo, fd [][]float64, w []float64, state json.RawMessage) error {
+	if err := f.initialize(m, fd); err != nil {
+		return err
+	}
+	if w != nil {
+		if err := f.weigh(m, w); err != nil {
+			return err
+		}
+	}
+	return f.unmarshal(m, mi, state)
+}
+
+// container returns a model container with the model described by the
+// model info trained on the rows of the data container passed in,
+// weighted as described by the weighting flags.
+func (f *modelFamily) container(mi modelInfo, dc data.Container) (*ml.ModelContainer, error) {
+	m, err := f.fit(mi, dc.FilterWithPredict(mi.Features), rowWeights(dc))
+	if err != nil {
+		return nil, err
+	}
+	mc := ml.NewModelContainer(m, mi.name(), mi.Features)
+	mc.TransformDimension = int(mi.TransformDimension)
+	mc.TransformID = mi.TransformID
+	return mc, nil
+}
+
+// checkParams returns an error if the model info has a hyperparameter
+// that is not in the schema of the family.
+func (f *modelFamily) checkParams(mi modelInfo) error {
+	schema := f.params
+	if f.wrapped != nil {
+		if g := f.wrapped(mi); g != nil {
+			schema = append(append([]hyperparameter{}, schema...), g.params...)
+		}
+	}
+	for name := range mi.Params {
+		known := false
+		for _, p := range schema {
+			known = known || p.name == name
+		}
+		if !known {
+			return fmt.Errorf("unknown hyperparameter %v for %v models", name, f.name)
+		}
+	}
+	return nil
+}
+
+// paramsLabel returns the hyperparameters of the model info that are in
+// the schema of the family as they appear in model names.
+func (f *modelFamily) paramsLabel(mi modelInfo) (label string) {
+	for _, p := range f.params {
+		if v, ok := mi.Params[p.name]; ok {
+			label += fmt.Sprintf(" %v %v", p.name, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/forest"
+	"github.com/santiaago/kaggle/tree"
+	"github.com/santiaago/ml"
+)
+
+// forestState is the learned state of a random forest: the seed and the
+// nodes of each tree.
+type forestState struct {
+	Seeds []int64
+	Trees []*tree.Node
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   randomForest,
+		name:    "forest",
+		enabled: trainForest,
+		params: []hyperparameter{
+			{"trees", "number of trees."},
+			{"features", "number of features tried at each split, 0 is the square root of their number."},
+			{"bootstrap", "grow each tree on a bootstrap sample of the training points."},
+			{"depth", "maximum depth of the trees."},
+			{"minLeaf", "minimum number of training points in a leaf."},
+			{"criterion", "impurity criterion of the splits: gini or entropy."},
+			{"seed", "seed of the seeds of the trees."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*forest.Forest)
+			return ok
+		},
+		hyperparameters: forestHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			f := forest.NewForest()
+			f.TransformFunction, f.HasTransform = mi.transformFunction()
+			f.Folds = *folds
+			f.Trees = mi.intParam("trees", f.Trees)
+			f.Features = mi.intParam("features", f.Features)
+			f.Bootstrap = mi.param("bootstrap", fmt.Sprint(f.Bootstrap)) == "true"
+			f.MaxDepth = mi.intParam("depth", f.MaxDepth)
+			f.MinLeaf = mi.intParam("minLeaf", f.MinLeaf)
+			f.Criterion = mi.param("criterion", f.Criterion)
+			f.Seed = int64(mi.intParam("seed", int(f.Seed)))
+			return f
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			f := m.(*forest.Forest)
+			if err := f.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if f.HasTransform {
+				return f.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*forest.Forest).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*forest.Forest).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*forest.Forest).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			f := m.(*forest.Forest)
+			return classify.Scores(f.Probability, &f.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			f := m.(*forest.Forest)
+			mi.setParam("trees", f.Trees)
+			mi.setParam("features", f.Features)
+			mi.setParam("bootstrap", f.Bootstrap)
+			mi.setParam("depth", f.MaxDepth)
+			mi.setParam("minLeaf", f.MinLeaf)
+			mi.setParam("criterion", f.Criterion)
+			mi.setParam("seed", f.Seed)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			f := m.(*forest.Forest)
+			state := forestState{Seeds: f.Seeds}
+			for _, t := range f.Members {
+				state.Trees = append(state.Trees, t.Root)
+			}
+			return json.Marshal(state)
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			f := m.(*forest.Forest)
+			var s forestState
+			if err := json.Unmarshal(state, &s); err != nil {
+				return err
+			}
+			if len(s.Seeds) != len(s.Trees) {
+				return fmt.Errorf("forest with %v seeds and %v trees", len(s.Seeds), len(s.Trees))
+			}
+			f.ResetErrors()
+			f.Seeds = s.Seeds
+			f.Members = nil
+			for _, root := range s.Trees {
+				t := tree.NewTree()
+				t.Root = root
+				f.Members = append(f.Members, t)
+			}
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*forest.Forest).Members) > 0
+		},
+	})
+}
+
+// forestHyperparameters returns a model info for every combination of the
+// values of the forest flags.
+//
+func forestHyperparameters() (mis []modelInfo) {
+	trees, err := intRange(*forestTrees)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	features, err := intRange(*forestFeatures)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	depths, err := intRange(*forestDepth)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	minLeaves, err := intRange(*forestMinLeaf)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	criteria, err := stringList(*forestCriterion, tree.Gini, tree.Entropy)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, n := range trees {
+		for _, f := range features {
+			for _, d := range depths {
+				for _, l := range minLeaves {
+					for _, c := range criteria {
+						mi := modelInfo{Model: randomForest}
+						mi.setParam("trees", n)
+						mi.setParam("features", f)
+						mi.setParam("bootstrap", *forestBootstrap)
+						mi.setParam("depth", d)
+						mi.setParam("minLeaf", l)
+						mi.setParam("criterion", c)
+						mi.setParam("seed", *forestSeed)
+						mis = append(mis, mi)
+					}
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+var (
+	// trainCtx is cancelled when the program receives SIGINT or SIGTERM
+	// during the training or when the deadline is reached, see handleInterrupts.
+	trainCtx = context.Background()
+
+	// stopInterrupts restores the default behavior of SIGINT and SIGTERM
+	// and cancels trainCtx. It is called once the training is over.
+	stopInterrupts = func() {}
+
+	// partialResults is true when the training was interrupted, so the
+	// rankings and exported models only hold the models trained so far.
+	partialResults bool
+)
+
+// handleInterrupts cancels trainCtx on the first SIGINT or SIGTERM, or when
+// the deadline flag is reached, so that the remaining training is skipped and
+// the models trained so far are still ranked and exported.
+// A second signal terminates the program.
+func handleInterrupts() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopDeadline := func() {}
+	if *deadline > 0 {
+		ctx, stopDeadline = context.WithTimeout(ctx, *deadline)
+	}
+	trainCtx = ctx
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			fmt.Println("\ninterrupted: skipping remaining training, ranking and exporting partial results.")
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+			if ctx.Err() == context.DeadlineExceeded {
+				fmt.Printf("\ndeadline of %v reached: skipping remaining training, ranking and exporting partial results.\n", *deadline)
+			}
+		case <-done:
+		}
+	}()
+
+	// the contexts are cancelled once the training is over so that the
+	// deadline timer is released and the abandoned fits stop.
+	stopInterrupts = func() {
+		signal.Stop(signals)
+		close(done)
+		stopDeadline()
+		cancel()
+		stopInterrupts = func() {}
+	}
+}
+
+// interrupted returns true if the training has been interrupted.
+func interrupted() bool {
+	return trainCtx.Err() != nil
+}
+
+// partialReason returns why the training stopped before its end.
+func partialReason() string {
+	if trainCtx.Err() == context.DeadlineExceeded {
+		return fmt.Sprintf("deadline of %v reached", *deadline)
+	}
+	return "training was interrupted"
+}
+
+// partialName returns the name passed in with a partial suffix
+// before its extension if the training was interrupted.
+func partialName(name string) string {
+	if !partialResults {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + ".partial" + ext
+}
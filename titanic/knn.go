@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/knn"
+	"github.com/santiaago/ml"
+)
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   kNearestNeighbours,
+		name:    "knn",
+		enabled: trainKnn,
+		params: []hyperparameter{
+			{"k", "number of neighbours."},
+			{"distance", "distance between points: euclid or manhattan."},
+			{"weighting", "weighting of the votes of the neighbours: uniform or distance."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*knn.KNN)
+			return ok
+		},
+		hyperparameters: knnHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			k := knn.NewKNN()
+			k.TransformFunction, k.HasTransform = mi.transformFunction()
+			k.Folds = *folds
+			k.K = mi.intParam("k", k.K)
+			k.Distance = mi.param("distance", k.Distance)
+			k.Weighting = mi.param("weighting", k.Weighting)
+			return k
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			k := m.(*knn.KNN)
+			if err := k.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if k.HasTransform {
+				return k.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*knn.KNN).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*knn.KNN).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*knn.KNN).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			k := m.(*knn.KNN)
+			return classify.Scores(k.Probability, &k.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			k := m.(*knn.KNN)
+			mi.setParam("k", k.K)
+			mi.setParam("distance", k.Distance)
+			mi.setParam("weighting", k.Weighting)
+		},
+		// the training points are the model, they are set by initialize.
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			return json.RawMessage("null"), nil
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return m.(*knn.KNN).TrainingPoints > 0
+		},
+	})
+}
+
+// knnHyperparameters returns a model info for every combination of the
+// values of the knnK, knnDist and knnWeight flags.
+//
+func knnHyperparameters() (mis []modelInfo) {
+	ks, err := intRange(*knnK)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	distances, err := stringList(*knnDistance, knn.Euclidean, knn.Manhattan)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	weightings, err := stringList(*knnWeighting, knn.Uniform, knn.Distance)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, k := range ks {
+		for _, d := range distances {
+			for _, w := range weightings {
+				mi := modelInfo{Model: kNearestNeighbours}
+				mi.setParam("k", k)
+				mi.setParam("distance", d)
+				mi.setParam("weighting", w)
+				mis = append(mis, mi)
+			}
+		}
+	}
+	return
+}
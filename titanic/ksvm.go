@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/ksvm"
+	"github.com/santiaago/ml"
+)
+
+// ksvmState is the learned state of a kernel SVM.
+type ksvmState struct {
+	Mean    []float64
+	Scale   []float64
+	Support [][]float64
+	Coef    []float64
+	Rho     float64
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   kernelSVM,
+		name:    "ksvm",
+		enabled: trainKsvm,
+		params: []hyperparameter{
+			{"kernel", "kernel of the svm: rbf or poly."},
+			{"C", "penalty of the margin violations."},
+			{"gamma", "scale of the kernel, 0 is 1 over the number of features."},
+			{"degree", "degree of the polynomial kernel."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*ksvm.KernelSVM)
+			return ok
+		},
+		hyperparameters: ksvmHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			s := ksvm.NewKernelSVM()
+			s.TransformFunction, s.HasTransform = mi.transformFunction()
+			s.Folds = *folds
+			s.Kernel = mi.param("kernel", s.Kernel)
+			s.C = mi.floatParam("C", s.C)
+			s.Gamma = mi.floatParam("gamma", s.Gamma)
+			s.Degree = mi.intParam("degree", s.Degree)
+			return s
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			s := m.(*ksvm.KernelSVM)
+			if err := s.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if s.HasTransform {
+				return s.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*ksvm.KernelSVM).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*ksvm.KernelSVM).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*ksvm.KernelSVM).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			s := m.(*ksvm.KernelSVM)
+			return classify.Scores(s.Decision, &s.Data, x)
+		},
+		describe: func(m ml.Model, mi *modelInfo) {
+			s := m.(*ksvm.KernelSVM)
+			mi.setParam("kernel", s.Kernel)
+			mi.setParam("C", s.C)
+			mi.setParam("gamma", s.Gamma)
+			// the degree only matters to polynomial kernels.
+			if s.Kernel == ksvm.Poly {
+				mi.setParam("degree", s.Degree)
+			}
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			s := m.(*ksvm.KernelSVM)
+			return json.Marshal(ksvmState{s.Mean, s.Scale, s.Support, s.Coef, s.Rho})
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			s := m.(*ksvm.KernelSVM)
+			var st ksvmState
+			if err := json.Unmarshal(state, &st); err != nil {
+				return err
+			}
+			s.ResetErrors()
+			s.Mean, s.Scale, s.Support, s.Coef, s.Rho = st.Mean, st.Scale, st.Support, st.Coef, st.Rho
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*ksvm.KernelSVM).Mean) > 0
+		},
+	})
+}
+
+// ksvmHyperparameters returns a model info for every point of the grid
+// defined by the ksvm flags. The degrees are only combined with the
+// polynomial kernel.
+//
+func ksvmHyperparameters() (mis []modelInfo) {
+	kernels, err := stringList(*ksvmKernel, ksvm.RBF, ksvm.Poly)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	cs, err := floatList(*ksvmC)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	gammas, err := floatList(*ksvmGamma)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	degrees, err := intRange(*ksvmDegree)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, k := range kernels {
+		ds := degrees
+		if k != ksvm.Poly {
+			ds = []int{0}
+		}
+		for _, c := range cs {
+			for _, g := range gammas {
+				for _, d := range ds {
+					mi := modelInfo{Model: kernelSVM}
+					mi.setParam("kernel", k)
+					mi.setParam("C", c)
+					mi.setParam("gamma", g)
+					if k == ksvm.Poly {
+						mi.setParam("degree", d)
+					}
+					mis = append(mis, mi)
+				}
+			}
+		}
+	}
+	return
+}
@@ -1,88 +1,96 @@
 package main
 
 import (
-	"fmt"
+	"encoding/json"
 
-	"github.com/santiaago/kaggle/itertools"
+	"github.com/santiaago/kaggle/classify"
 	"github.com/santiaago/ml"
-	"github.com/santiaago/ml/data"
 	"github.com/santiaago/ml/logreg"
 )
 
-// logregTest sets the Survived field of each passenger in the passenger array
-// with respect to the prediction set by the linear regression 'linreg' passed as argument.
-//
-func logregTest(model *ml.ModelContainer, dc data.Container) ([]float64, error) {
-
-	fd := dc.Filter(model.Features)
-	lr, ok := model.Model.(*logreg.LogisticRegression)
-	if !ok {
-		return nil, fmt.Errorf("not a logistic regression")
-	}
-	return lr.Predictions(fd)
-}
-
-// logregCombinations creates a logistic regression model for each combination of
-// the feature vector with respect to the size param.
-// It returns an array of linear regressions, one for each combination.
-// todo(santiaago): move to ml
-//
-func logregCombinations(dc data.Container, size int) (models ml.ModelContainers) {
-
-	combs := itertools.Combinations(dc.Features, size)
-
-	for _, c := range combs {
-		fmt.Printf("\r%v/%v", c, len(combs))
-		fd := dc.FilterWithPredict(c)
-		lr := logreg.NewLogisticRegression()
-		lr.InitializeFromData(fd)
-
-		if err := lr.Learn(); err != nil {
-			continue
-		}
-		name := fmt.Sprintf("Logreg 1D %v epochs-%v", c, lr.Epochs)
-
-		models = append(models, ml.NewModelContainer(lr, name, c))
-	}
-	fmt.Println()
-	return
-}
-
-func specificLogregModels(dc data.Container) (models ml.ModelContainers) {
-
-	cases := []struct {
-		features []int
-		name     string
-	}{
-		{
-			[]int{passengerIndexSex, passengerIndexAge},
-			"logreg Sex Age",
+func init() {
+	registerFamily(&modelFamily{
+		model:         logisticRegression,
+		name:          "logreg",
+		enabled:       trainLogreg,
+		regularizable: true,
+		is: func(m ml.Model) bool {
+			_, ok := unwrap(m).(*logreg.LogisticRegression)
+			return ok
 		},
-		{
-			[]int{passengerIndexAge, passengerIndexPclass},
-			"logreg PClass Age",
+		hyperparameters: func() []modelInfo {
+			return []modelInfo{{Model: logisticRegression}}
 		},
-
-		{
-			[]int{passengerIndexSex, passengerIndexPclass},
-			"logreg PClass Sex",
+		newModel: func(mi modelInfo) ml.Model {
+			lr := logreg.NewLogisticRegression()
+			lr.TransformFunction, lr.HasTransform = mi.transformFunction()
+			lr.IsRegularized = mi.Regularized
+			lr.K = mi.K
+			return lr
 		},
-		{
-			[]int{passengerIndexSex, passengerIndexAge, passengerIndexPclass},
-			"logreg Sex Age PClass",
+		initialize: func(m ml.Model, fd [][]float64) error {
+			lr := unwrap(m).(*logreg.LogisticRegression)
+			lr.InitializeFromData(fd)
+			if lr.HasTransform {
+				return lr.ApplyTransformation()
+			}
+			return nil
 		},
-	}
-
-	for _, c := range cases {
-		lr := logreg.NewLogisticRegression()
-		fd := dc.FilterWithPredict(c.features)
-		lr.InitializeFromData(fd)
+		weigh: func(m ml.Model, w []float64) error {
+			lr := unwrap(m).(*logreg.LogisticRegression)
+			var err error
+			lr.Xn, lr.Yn, err = resampled(lr.Xn, lr.Yn, w)
+			return err
+		},
+		resampled: true,
+		learn:     logregLearn,
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return unwrap(m).(*logreg.LogisticRegression).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			lr := unwrap(m).(*logreg.LogisticRegression)
+			scores, err := linearScores(lr.Wn, lr.TransformFunction, lr.HasTransform, x)
+			for i, s := range scores {
+				scores[i] = classify.Logistic(s)
+			}
+			return scores, err
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			if lr := unwrap(m).(*logreg.LogisticRegression); lr.IsRegularized {
+				mi.Regularized = true
+				mi.K = lr.K
+			}
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			return json.Marshal(unwrap(m).(*logreg.LogisticRegression).Wn)
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			lr := unwrap(m).(*logreg.LogisticRegression)
+			lr.IsRegularized = mi.Regularized
+			lr.K = mi.K
+			return json.Unmarshal(state, &lr.Wn)
+		},
+		learned: func(m ml.Model) bool {
+			return len(unwrap(m).(*logreg.LogisticRegression).Wn) > 0
+		},
+	})
+}
 
-		if err := lr.Learn(); err != nil {
-			continue
-		}
-		mc := ml.NewModelContainer(lr, c.name, c.features)
-		models = append(models, mc)
+// logregLearn trains the logistic regression passed in.
+// If the model info is regularized, the weights are learned with
+// regularization, lambda = 10^-k.
+//
+func logregLearn(m ml.Model, mi modelInfo) error {
+	lr := unwrap(m).(*logreg.LogisticRegression)
+	if !mi.Regularized {
+		return lr.Learn()
+	}
+	lr.K = mi.K
+	if err := lr.LearnRegularized(); err != nil {
+		return err
 	}
-	return
+	lr.Wn = lr.WReg
+	lr.IsRegularized = true
+	return nil
 }
@@ -4,14 +4,21 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/santiaago/ml"
 )
 
 var (
+	configPath = flag.String("config", "", "path to a json experiment file. Flags set on the command line override its values.")
+
 	testSrc  = flag.String("testSrc", "data/test.csv", "testing set.")
 	trainSrc = flag.String("trainSrc", "data/train.csv", "training set.")
 
+	classWeight       = flag.String("classWeight", "", "weights of the classes when training: balanced, so that both classes weigh the same, or 'died,survived'. Empty weighs both by 1.")
+	sampleWeightsPath = flag.String("sampleWeights", "", "csv file PassengerId,Weight of the weights of the training passengers, multiplied by their class weight. Passengers not in the file weigh 1.")
+
 	test = flag.Bool("test", false, "run test on test source and write to predictions to files.")
 
 	importPath      = flag.String("ipath", "models.json", "path to a json array with models to use description.")
@@ -25,18 +32,78 @@ var (
 	trainLinreg = flag.Bool("linreg", false, "train linear regressions.")
 	trainLogreg = flag.Bool("logreg", false, "train logistic regressions.")
 	trainSvm    = flag.Bool("svm", false, "train support vector machines.")
+	trainKsvm   = flag.Bool("ksvm", false, "train kernel support vector machines.")
+	trainKnn    = flag.Bool("knn", false, "train k-nearest-neighbours classifiers.")
+	trainTree   = flag.Bool("tree", false, "train decision trees.")
+	trainForest = flag.Bool("forest", false, "train random forests.")
+	trainBoost  = flag.Bool("boost", false, "train gradient boosted trees.")
+	trainPla    = flag.Bool("pla", false, "train perceptrons with the pla or the pocket algorithm.")
+	trainMlp    = flag.Bool("mlp", false, "train multilayer perceptrons, feed-forward neural networks of one or two hidden layers.")
+	trainBag    = flag.Bool("bag", false, "train bags of models of the families of bagFamily on bootstrap samples, voting by majority. Their Ecv is the out of bag error.")
+	trainBayes  = flag.Bool("nb", false, "train naive Bayes classifiers, Gaussian for continuous features and categorical for discrete ones.")
 
 	trainSpecific      = flag.Bool("specific", false, "train specific models.")
-	combinations       = flag.Int("comb", 0, "number of features to try with all combinations.")
+	combinations       = flag.String("comb", "", "number of features to try with all combinations: a size 'n', a range of sizes 'from:to' or 'all'. Empty or 0 disables the combinations.")
+	featureSelection   = flag.String("select", "", "stepwise feature selection strategy by cross validation error: forward, backward or floating.")
 	trainTransforms    = flag.Bool("trans", false, "train models with transformations.")
 	transformDimension = flag.Int("dim", 0, "dimension of transformation.")
 	trainRegularized   = flag.Bool("reg", false, "train models with regularization.")
+	regK               = flag.String("regK", "-5:5", "range of k values to try when regularizing, lambda = 10^-k: 'k', 'from:to' or 'from:to:step'.")
+	folds              = flag.Int("folds", 10, "number of folds used for cross validation.")
 
 	svmKRange = flag.Int("svmKRange", 1, "range of number of block size that should be try for the svm pegasos algorithm. If k = 10, we will try all values from 1 to k")
 	svmK      = flag.Int("svmK", 1, "number of block size that should be try for the svm pegasos algorithm.")
 	svmLambda = flag.Float64("svmL", 0.001, "lambda, regularization parameter.")
 	svmT      = flag.Int("svmT", 1000, "number of iterations for svm Pegasos algorithm.")
 
+	knnK         = flag.String("knnK", "5", "number of neighbours of the knn classifiers: 'k', 'from:to' or 'from:to:step'.")
+	knnDistance  = flag.String("knnDist", "euclid", "comma separated distances of the knn classifiers: euclid, manhattan.")
+	knnWeighting = flag.String("knnWeight", "uniform", "comma separated weightings of the votes of the knn classifiers: uniform, distance.")
+
+	treeDepth     = flag.String("treeDepth", "5", "maximum depth of the decision trees: 'd', 'from:to' or 'from:to:step'.")
+	treeMinLeaf   = flag.String("treeMinLeaf", "5", "minimum number of training points in a leaf of the decision trees: 'n', 'from:to' or 'from:to:step'.")
+	treeCriterion = flag.String("treeCriterion", "gini", "comma separated impurity criteria of the decision trees: gini, entropy.")
+
+	forestTrees     = flag.String("forestTrees", "100", "number of trees of the random forests: 'n', 'from:to' or 'from:to:step'.")
+	forestFeatures  = flag.String("forestFeatures", "0", "number of features tried at each split of the random forests, 0 is the square root of their number: 'n', 'from:to' or 'from:to:step'.")
+	forestBootstrap = flag.Bool("forestBootstrap", true, "grow the trees of the random forests on bootstrap samples. Their Ecv is then the out of bag error.")
+	forestDepth     = flag.String("forestDepth", "10", "maximum depth of the trees of the random forests: 'd', 'from:to' or 'from:to:step'.")
+	forestMinLeaf   = flag.String("forestMinLeaf", "1", "minimum number of training points in a leaf of the random forests: 'n', 'from:to' or 'from:to:step'.")
+	forestCriterion = flag.String("forestCriterion", "gini", "comma separated impurity criteria of the random forests: gini, entropy.")
+	forestSeed      = flag.Int("forestSeed", 1, "seed of the random forests, it defines the bootstrap samples and the features tried by each tree.")
+
+	boostRounds    = flag.String("boostRounds", "100", "maximum number of trees of the gradient boosting: 'n', 'from:to' or 'from:to:step'.")
+	boostRate      = flag.String("boostRate", "0.1", "comma separated learning rates of the gradient boosting.")
+	boostDepth     = flag.String("boostDepth", "3", "maximum depth of the trees of the gradient boosting: 'd', 'from:to' or 'from:to:step'.")
+	boostMinLeaf   = flag.Int("boostMinLeaf", 5, "minimum number of training points in a leaf of the gradient boosting.")
+	boostSubsample = flag.String("boostSubsample", "1", "comma separated fractions of the training points used to fit each tree of the gradient boosting.")
+	boostStop      = flag.Int("boostStop", 0, "stop the gradient boosting after this number of rounds without improvement of the loss on a validation fold, 0 disables early stopping.")
+	boostSeed      = flag.Int("boostSeed", 1, "seed of the subsamples of the gradient boosting.")
+
+	nbSmoothing = flag.String("nbSmoothing", "1", "comma separated additive smoothings of the categorical likelihoods of the naive Bayes classifiers.")
+
+	plaAlgorithm  = flag.String("plaAlgorithm", "pocket", "comma separated algorithms of the perceptrons: pla keeps the last weights, pocket the weights with the lowest in sample error.")
+	plaIterations = flag.String("plaIterations", "1000", "maximum number of iterations of the perceptrons: 'n', 'from:to' or 'from:to:step'.")
+	plaSeed       = flag.Int("plaSeed", 1, "seed of the choice of the misclassified point of each iteration of the perceptrons.")
+
+	ksvmKernel = flag.String("ksvmKernel", "rbf", "comma separated kernels of the kernel svms: rbf, poly.")
+	ksvmC      = flag.String("ksvmC", "1", "comma separated penalties C of the margin violations of the kernel svms.")
+	ksvmGamma  = flag.String("ksvmGamma", "0", "comma separated scales gamma of the kernels of the kernel svms, 0 is 1 over the number of features.")
+	ksvmDegree = flag.String("ksvmDegree", "3", "degrees of the polynomial kernels of the kernel svms: 'd', 'from:to' or 'from:to:step'.")
+
+	mlpHidden     = flag.String("mlpHidden", "8", "comma separated widths of the hidden layers of the multilayer perceptrons: 'w' for one layer, 'w1xw2' for two layers.")
+	mlpActivation = flag.String("mlpActivation", "relu", "comma separated activations of the hidden units of the multilayer perceptrons: relu, tanh, sigmoid.")
+	mlpL2         = flag.String("mlpL2", "0.0001", "comma separated L2 penalties of the weights of the multilayer perceptrons.")
+	mlpRate       = flag.String("mlpRate", "0.01", "comma separated learning rates of the multilayer perceptrons.")
+	mlpEpochs     = flag.String("mlpEpochs", "100", "maximum number of epochs of the multilayer perceptrons: 'n', 'from:to' or 'from:to:step'.")
+	mlpBatch      = flag.Int("mlpBatch", 32, "number of training points of each mini-batch of the multilayer perceptrons.")
+	mlpStop       = flag.Int("mlpStop", 0, "stop the training of the multilayer perceptrons after this number of epochs without improvement of the loss on a validation fold, 0 disables early stopping.")
+	mlpSeed       = flag.Int("mlpSeed", 1, "seed of the initial weights and of the order of the training points of the multilayer perceptrons.")
+
+	bagFamily = flag.String("bagFamily", "logreg", "comma separated names of the families of the bagged models, trained with the hyperparameters of their own flags.")
+	bagSize   = flag.String("bagSize", "25", "number of bootstrap samples of the bags: 'n', 'from:to' or 'from:to:step'.")
+	bagSeed   = flag.Int("bagSeed", 1, "seed of the bootstrap samples of the bags.")
+
 	svmKOverride      = flag.Bool("osvmK", false, "override svmK.")
 	svmLambdaOverride = flag.Bool("osvmL", false, "override svmL.")
 	svmTOverride      = flag.Bool("osvmT", false, "override svmT.")
@@ -46,15 +113,53 @@ var (
 
 	topN = flag.Int("top", 10, "exports the top N models")
 
+	calibrations    = flag.String("calibrate", "", "comma separated calibrations of the scores of the top N models fitted on cross validation folds: platt, isotonic. Reliability tables of their probabilities are written to calibration.md in the temp folder. Empty disables calibration.")
+	calibrationBins = flag.Int("calibrationBins", 10, "number of bins of the reliability tables.")
+
+	thresholdBy = flag.String("threshold", "", "metric maximized by the decision thresholds of the top N models tuned on their out of fold scores: accuracy, f1, balanced. The thresholds are written to thresholds.md in the temp folder, exported with the models and used by the test predictions. Empty keeps the usual predictions.")
+
+	ensembleVotes = flag.String("ensemble", "", "comma separated votes of the ensembles of the top N models ranked along with them: hard, weighted by 1 - Ecv, stacked by a logistic regression. Empty disables ensembles.")
+
+	fitTimeout = flag.Duration("fitTimeout", 0, "time budget of a single model fit, e.g. 30s. Fits exceeding it are abandoned and ranked as timed out. 0 means no limit.")
+	deadline   = flag.Duration("deadline", 0, "time budget of the whole training, e.g. 1h. When it is reached the models trained so far are ranked and exported. 0 means no limit.")
+
+	checkpointEvery = flag.Int("checkpointEvery", 10, "number of trained models between two writes of the checkpoint file in the temp folder, 0 disables checkpoints.")
+	resume          = flag.Bool("resume", false, "resume a previous run: configurations found in the checkpoint file are not trained again.")
+
+	cachePath    = flag.String("cache", "", "path of the folder of the trained models cache shared by every run, e.g. data/cache/. Empty disables the cache.")
+	cacheMaxAge  = flag.Duration("cacheMaxAge", 0, "the cache command removes the cached models not used for longer than this duration, e.g. 720h. 0 means no limit.")
+	cacheMaxSize = flag.Int64("cacheMaxSize", 0, "the cache command removes the least recently used models until the cache holds at most this number of MB. 0 means no limit.")
+
 	verbose = flag.Bool("v", false, "verbose: print additional output")
+	quiet   = flag.Bool("quiet", false, "quiet: print a line at the end of each training stage instead of the progress line, useful when the output is logged.")
+
+	cpuProfile = flag.String("cpuprofile", "cpu.prof", "name of the cpu profile written to the temp folder by the profile command.")
+	memProfile = flag.String("memprofile", "mem.prof", "name of the memory profile written to the temp folder by the profile command.")
 )
 
 func init() {
 	log.SetFlags(log.Ltime | log.Ldate | log.Lshortfile)
 }
 
+// main runs the subcommand passed as first argument, see commands.go.
+// When the first argument is a flag, the program runs the original
+// flag-driven pipeline: train, test, rank and export.
+//
 func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	flag.Usage = func() {
+		usage()
+		fmt.Fprintf(os.Stderr, "\nflags when no command is given:\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	setup(flag.CommandLine)
 
 	var models ml.ModelContainers
 	if models = trainModels(); len(models) == 0 {
@@ -71,7 +176,43 @@ func main() {
 	exportModels(models, *exportPath)
 }
 
+// setup loads the experiment file if any and writes the resolved
+// experiment to the temp folder.
+// fs is the flag set that parsed the command line.
+//
+func setup(fs *flag.FlagSet) {
+	if *configPath != "" {
+		if err := loadExperiment(fs, *configPath); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if err := writeExperiment("experiment.json"); err != nil {
+		log.Println(err)
+	}
+}
+
+// rank ranks the models passed in and returns the top ones.
+// When the calibrate flag is set, the reliability tables of the top models
+// are written. When the threshold flag is set, the decision thresholds of
+// the top models are tuned. When the ensemble flag is set, ensembles of the
+// top models, voting with their tuned thresholds, are then tested and
+// ranked along with them.
+//
 func rank(models ml.ModelContainers) ml.ModelContainers {
+	models = rankModels(models)
+	// the calibration and the threshold tuning share the out of fold scores.
+	oof := newOutOfFoldScorer(trainData())
+	calibrateModels(models, oof, "calibration.md")
+	thresholdModels(models, oof, "thresholds.md")
+	if ensembles := ensembleModels(models); len(ensembles) > 0 {
+		testModels(ensembles)
+		models = rankModels(append(models, ensembles...))
+	}
+	writeTrees(models, "trees.md")
+	return models
+}
+
+func rankModels(models ml.ModelContainers) ml.ModelContainers {
 	if *verbose {
 		fmt.Println("Start ranking models")
 	}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/mlp"
+	"github.com/santiaago/ml"
+)
+
+// mlpState is the learned state of a multilayer perceptron.
+type mlpState struct {
+	Mean    []float64
+	Scale   []float64
+	Weights [][][]float64
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   multilayerPerceptron,
+		name:    "mlp",
+		enabled: trainMlp,
+		params: []hyperparameter{
+			{"hidden", "widths of the hidden layers, 'w' or 'w1xw2'."},
+			{"activation", "activation of the hidden units: relu, tanh or sigmoid."},
+			{"l2", "penalty of the squared weights."},
+			{"rate", "learning rate of the gradient descent."},
+			{"epochs", "maximum number of passes over the training points."},
+			{"batch", "number of training points of each gradient step."},
+			{"stop", "epochs without improvement of the validation loss before stopping, 0 disables it."},
+			{"seed", "seed of the initial weights and of the order of the training points."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*mlp.MLP)
+			return ok
+		},
+		hyperparameters: mlpHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			n := mlp.NewMLP()
+			n.TransformFunction, n.HasTransform = mi.transformFunction()
+			n.Folds = *folds
+			if hidden, err := hiddenLayers(mi.param("hidden", hiddenName(n.Hidden))); err == nil {
+				n.Hidden = hidden
+			} else {
+				log.Println(err)
+				n.Hidden = nil
+			}
+			n.Activation = mi.param("activation", n.Activation)
+			n.L2 = mi.floatParam("l2", n.L2)
+			n.LearningRate = mi.floatParam("rate", n.LearningRate)
+			n.Epochs = mi.intParam("epochs", n.Epochs)
+			n.BatchSize = mi.intParam("batch", n.BatchSize)
+			n.EarlyStopping = mi.intParam("stop", n.EarlyStopping)
+			n.Seed = int64(mi.intParam("seed", int(n.Seed)))
+			return n
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			n := m.(*mlp.MLP)
+			if err := n.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if n.HasTransform {
+				return n.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*mlp.MLP).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*mlp.MLP).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*mlp.MLP).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			n := m.(*mlp.MLP)
+			return classify.Scores(n.Probability, &n.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			n := m.(*mlp.MLP)
+			mi.setParam("hidden", hiddenName(n.Hidden))
+			mi.setParam("activation", n.Activation)
+			mi.setParam("l2", n.L2)
+			mi.setParam("rate", n.LearningRate)
+			mi.setParam("epochs", n.Epochs)
+			mi.setParam("batch", n.BatchSize)
+			mi.setParam("stop", n.EarlyStopping)
+			mi.setParam("seed", n.Seed)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			n := m.(*mlp.MLP)
+			return json.Marshal(mlpState{n.Mean, n.Scale, n.Weights})
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			n := m.(*mlp.MLP)
+			var st mlpState
+			if err := json.Unmarshal(state, &st); err != nil {
+				return err
+			}
+			n.ResetErrors()
+			n.Mean, n.Scale, n.Weights = st.Mean, st.Scale, st.Weights
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*mlp.MLP).Weights) > 0
+		},
+	})
+}
+
+// hiddenLayers returns the widths of the hidden layers described by h,
+// 'w' for one layer or 'w1xw2' for two layers.
+//
+func hiddenLayers(h string) (widths []int, err error) {
+	for _, w := range strings.Split(h, "x") {
+		var width int
+		if width, err = strconv.Atoi(strings.TrimSpace(w)); err != nil || width < 1 {
+			return nil, fmt.Errorf("invalid hidden layers %v", h)
+		}
+		widths = append(widths, width)
+	}
+	if len(widths) > 2 {
+		return nil, fmt.Errorf("invalid hidden layers %v, at most 2 layers", h)
+	}
+	return
+}
+
+// hiddenName returns the description of the hidden layers passed in,
+// see hiddenLayers.
+//
+func hiddenName(widths []int) string {
+	s := make([]string, len(widths))
+	for i, w := range widths {
+		s[i] = strconv.Itoa(w)
+	}
+	return strings.Join(s, "x")
+}
+
+// mlpHyperparameters returns a model info for every point of the grid
+// defined by the mlp flags.
+//
+func mlpHyperparameters() (mis []modelInfo) {
+	var hidden []string
+	for _, h := range strings.Split(*mlpHidden, ",") {
+		widths, err := hiddenLayers(h)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		hidden = append(hidden, hiddenName(widths))
+	}
+	activations, err := stringList(*mlpActivation, mlp.ReLU, mlp.Tanh, mlp.Sigmoid)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	l2s, err := floatList(*mlpL2)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	rates, err := floatList(*mlpRate)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	epochs, err := intRange(*mlpEpochs)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, h := range hidden {
+		for _, a := range activations {
+			for _, l2 := range l2s {
+				for _, r := range rates {
+					for _, e := range epochs {
+						mi := modelInfo{Model: multilayerPerceptron}
+						mi.setParam("hidden", h)
+						mi.setParam("activation", a)
+						mi.setParam("l2", l2)
+						mi.setParam("rate", r)
+						mi.setParam("epochs", e)
+						mi.setParam("batch", *mlpBatch)
+						mi.setParam("stop", *mlpStop)
+						mi.setParam("seed", *mlpSeed)
+						mis = append(mis, mi)
+					}
+				}
+			}
+		}
+	}
+	return
+}
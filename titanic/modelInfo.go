@@ -5,13 +5,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strconv"
 
 	"github.com/santiaago/ml"
 	"github.com/santiaago/ml/data"
-	"github.com/santiaago/ml/linreg"
-	"github.com/santiaago/ml/logreg"
-	"github.com/santiaago/ml/svm"
-	"github.com/santiaago/ml/transform"
 )
 
 // ModelType defines the model that it is been used.
@@ -21,6 +18,15 @@ const (
 	linearRegression ModelType = iota
 	logisticRegression
 	supportVectorMachines
+	kNearestNeighbours
+	decisionTree
+	randomForest
+	gradientBoosting
+	naiveBayes
+	perceptron
+	kernelSVM
+	multilayerPerceptron
+	bagging
 )
 
 // Dimension defines the type of transformation used.
@@ -28,6 +34,7 @@ type Dimension int
 
 const (
 	NOT Dimension = 0
+	T2D Dimension = 2
 	T3D Dimension = 3
 	T4D Dimension = 4
 	T5D Dimension = 5
@@ -36,14 +43,20 @@ const (
 // modelInfo is a type that describes the model to use.
 //
 type modelInfo struct {
-	Model              ModelType // the model type, either logistic regression or linear regression.
-	TransformDimension Dimension // the transform dimension if any.
-	TransformID        int       // the id of the transformation function.
-	Features           []int     // the features to use for this model.
-	Regularized        bool      // flag to know if model is using regularization.
-	K                  int       // k param used in regularization or in svm.
-	T                  int       // param used in svm algorithm.
-	L                  float64   // param used in svm algorithm.
+	Model              ModelType         // the model type, see the registered model families.
+	TransformDimension Dimension         // the transform dimension if any.
+	TransformID        int               // the id of the transformation function.
+	Features           []int             // the features to use for this model.
+	Regularized        bool              // flag to know if model is using regularization.
+	K                  int               // k param used in regularization or in svm.
+	T                  int               // param used in svm algorithm.
+	L                  float64           // param used in svm algorithm.
+	Params             map[string]string `json:",omitempty"` // hyperparameters of the model family, see modelFamily.params.
+	State              json.RawMessage   `json:",omitempty"` // learned state of the model, see modelFamily.learned.
+	Threshold          *float64          `json:",omitempty"` // decision threshold of the scores of the model, see thresholdModels.
+	ClassWeight        string            `json:",omitempty"` // weights of the classes the model was trained with, see classWeights.
+	SampleWeights      string            `json:",omitempty"` // file of the weights of the passengers the model was trained with, see sampleWeight.
+	Resampled          bool              `json:",omitempty"` // true if the weights were applied by resampling the training points instead of weighing them, see resampleRows.
 }
 
 // ModelInfoFromModel returns a modelInfo type from
@@ -51,40 +64,13 @@ type modelInfo struct {
 //
 func ModelInfoFromModel(m *ml.ModelContainer) (mi modelInfo) {
 
-	if lr, ok := m.Model.(*linreg.LinearRegression); ok {
-		mi.Model = linearRegression
-		if !lr.HasTransform {
-			mi.TransformDimension = NOT
-		}
-		mi.TransformDimension = Dimension(m.TransformDimension)
-		mi.TransformID = m.TransformID
-		if lr.IsRegularized {
-			mi.Regularized = true
-			mi.K = lr.K
-		}
-	} else if lr, ok := m.Model.(*logreg.LogisticRegression); ok {
-		mi.Model = logisticRegression
-		if !lr.HasTransform {
-			mi.TransformDimension = NOT
-		}
-		mi.TransformDimension = Dimension(m.TransformDimension)
-		mi.TransformID = m.TransformID
-		if lr.IsRegularized {
-			mi.Regularized = true
-			mi.K = lr.K
-		}
-	} else if svm, ok := m.Model.(*svm.SVM); ok {
-		mi.Model = supportVectorMachines
-		if !svm.HasTransform {
-			mi.TransformDimension = NOT
-		}
-		mi.TransformDimension = Dimension(m.TransformDimension)
-		mi.TransformID = m.TransformID
-		mi.K = svm.K
-		mi.T = svm.T
-		mi.L = svm.Lambda
-	}
+	mi.TransformDimension = Dimension(m.TransformDimension)
+	mi.TransformID = m.TransformID
 	mi.Features = m.Features
+	if f := familyOfModel(m.Model); f != nil {
+		mi.Model = f.model
+		f.describe(m.Model, &mi)
+	}
 	return
 }
 
@@ -93,39 +79,24 @@ func ModelInfoFromModel(m *ml.ModelContainer) (mi modelInfo) {
 //
 func (mi modelInfo) name() (name string) {
 
-	if mi.Model == linearRegression {
-		name = "linreg"
-	} else if mi.Model == logisticRegression {
-		name = "logreg"
-	} else if mi.Model == supportVectorMachines {
-		name = "svm"
+	f := familyOf(mi.Model)
+	if f == nil {
+		return fmt.Sprintf("unknown model %v %v", mi.Model, mi.Features)
 	}
+	name = f.name
 
-	if mi.TransformDimension == T3D {
-		name += " 3D"
-	} else if mi.TransformDimension == T4D {
-		name += " 4D"
-	} else if mi.TransformDimension == T5D {
-		name += " 5D"
+	if mi.TransformDimension > NOT {
+		name += fmt.Sprintf(" %dD", mi.TransformDimension)
+	} else {
+		name += " 1D"
 	}
 
 	name += fmt.Sprintf(" %v", mi.Features)
 
-	if mi.Model == supportVectorMachines {
-		k := mi.K
-		t := mi.T
-		l := mi.L
-		if *svmKOverride {
-			k = *svmK
-		}
-		if *svmTOverride {
-			t = *svmT
-		}
-		if *svmLambdaOverride {
-			l = *svmLambda
-		}
-		name += fmt.Sprintf(" k %v T %v L %v", k, t, l)
+	if f.label != nil {
+		name += f.label(mi)
 	}
+	name += f.paramsLabel(mi)
 
 	if mi.TransformDimension != NOT {
 		name += fmt.Sprintf(" transformed %v", mi.TransformID)
@@ -138,47 +109,74 @@ func (mi modelInfo) name() (name string) {
 	return
 }
 
-// newModel creates model type with respect to the model
-// info passed in.
+// transformFunction returns the transform function of the model info
+// and true if the model is transformed.
 //
-func (mi modelInfo) newModel() (m ml.Model) {
+func (mi modelInfo) transformFunction() (func([]float64) ([]float64, error), bool) {
 	// todo(santiaago): need ml.TransformFunc type
 	var transformFunc func([]float64) ([]float64, error)
 
-	if mi.TransformDimension == T3D {
-		transformFunc = transform.Funcs3D()[mi.TransformID]
-	} else if mi.TransformDimension == T4D {
-		transformFunc = transform.Funcs4D()[mi.TransformID]
-	} else if mi.TransformDimension == T5D {
-		transformFunc = transform.Funcs5D()[mi.TransformID]
+	if funcs := transformArray(int(mi.TransformDimension)); mi.TransformID < len(funcs) {
+		transformFunc = funcs[mi.TransformID]
 	}
+	return transformFunc, mi.TransformDimension > NOT
+}
 
-	if mi.Model == linearRegression {
-		m = linreg.NewLinearRegression()
-		m.(*linreg.LinearRegression).TransformFunction = transformFunc
-		if mi.TransformDimension > 0 {
-			m.(*linreg.LinearRegression).HasTransform = true
-		}
-	} else if mi.Model == logisticRegression {
-		m = logreg.NewLogisticRegression()
-		m.(*logreg.LogisticRegression).TransformFunction = transformFunc
-		if mi.TransformDimension > 0 {
-			m.(*logreg.LogisticRegression).HasTransform = true
-		}
-	} else if mi.Model == supportVectorMachines {
-		m = svm.NewSVM()
-		m.(*svm.SVM).TransformFunction = transformFunc
-		if mi.TransformDimension > 0 {
-			m.(*svm.SVM).HasTransform = true
-		}
-		m.(*svm.SVM).K = mi.K
-		m.(*svm.SVM).T = mi.T
-		m.(*svm.SVM).Lambda = mi.L
-		if *verbose {
-			fmt.Printf("setting svm with k: %v T: %v L: %v\n", mi.K, mi.T, mi.L)
+// param returns the hyperparameter of the model info with the name passed in
+// or the default value passed in if the model info does not have it.
+//
+func (mi modelInfo) param(name, def string) string {
+	if v, ok := mi.Params[name]; ok {
+		return v
+	}
+	return def
+}
+
+// intParam returns the hyperparameter of the model info with the name passed in
+// as an int, or the default value passed in if it is missing or invalid.
+//
+func (mi modelInfo) intParam(name string, def int) int {
+	v, err := strconv.Atoi(mi.param(name, strconv.Itoa(def)))
+	if err != nil {
+		log.Printf("invalid hyperparameter %v, %v", name, err)
+		return def
+	}
+	return v
+}
+
+// floatParam returns the hyperparameter of the model info with the name passed in
+// as a float, or the default value passed in if it is missing or invalid.
+//
+func (mi modelInfo) floatParam(name string, def float64) float64 {
+	v, err := strconv.ParseFloat(mi.param(name, strconv.FormatFloat(def, 'g', -1, 64)), 64)
+	if err != nil {
+		log.Printf("invalid hyperparameter %v, %v", name, err)
+		return def
+	}
+	return v
+}
+
+// setParam sets the hyperparameter of the model info with the name passed in.
+// The Params map is copied so that model infos never share it.
+//
+func (mi *modelInfo) setParam(name string, value interface{}) {
+	params := map[string]string{name: fmt.Sprint(value)}
+	for k, v := range mi.Params {
+		if k != name {
+			params[k] = v
 		}
 	}
-	return
+	mi.Params = params
+}
+
+// newModel creates model type with respect to the model
+// info passed in.
+//
+func (mi modelInfo) newModel() ml.Model {
+	if f := familyOf(mi.Model); f != nil {
+		return f.newModel(mi)
+	}
+	return nil
 }
 
 // model return a ml.Model with respect to the model information
@@ -186,84 +184,89 @@ func (mi modelInfo) newModel() (m ml.Model) {
 //
 func (mi modelInfo) GetModel(dc data.Container) *ml.Model {
 
-	m := mi.newModel()
-	if m == nil {
+	f := familyOf(mi.Model)
+	if f == nil {
+		return nil
+	}
+	m, err := f.fit(mi, dc.FilterWithPredict(mi.Features), rowWeights(dc))
+	if err != nil {
 		return nil
 	}
+	return &m
+}
 
-	fd := dc.FilterWithPredict(mi.Features)
+// readModelInfos returns the model infos of the model file passed in
+// whose family and hyperparameters are known, with the override flags applied.
+//
+func readModelInfos(path string) (modelInfos []modelInfo, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("unable to read file %v, %v", path, err)
+	}
 
-	if lr, ok := m.(*linreg.LinearRegression); ok {
+	var infos []modelInfo
+	if err = json.Unmarshal(b, &infos); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal bytes %v", err)
+	}
 
-		lr.InitializeFromData(fd)
-		if mi.TransformDimension > NOT {
-			lr.ApplyTransformation()
+	for _, mi := range infos {
+		f := familyOf(mi.Model)
+		if f == nil {
+			log.Printf("unknown model type %v in %v", mi.Model, path)
+			continue
 		}
-
-		if mi.Regularized {
-			lr.K = mi.K
-			if err := lr.LearnWeightDecay(); err != nil {
-				return nil
-			}
-			// todo(santiaago): update should be part of WeightDecay?
-			lr.Wn = lr.WReg
-		} else {
-			if err := lr.Learn(); err != nil {
-				return nil
-			}
+		if err := f.checkParams(mi); err != nil {
+			log.Printf("skipping model %v, %v", mi.name(), err)
+			continue
 		}
-	} else if lr, ok := m.(*logreg.LogisticRegression); ok {
-		lr.InitializeFromData(fd)
-		if mi.TransformDimension > NOT {
-			lr.ApplyTransformation()
+		if f.override != nil {
+			mi = f.override(mi)
 		}
+		modelInfos = append(modelInfos, mi)
+	}
+	return
+}
 
-		if mi.Regularized {
-			lr.K = mi.K
-			if err := lr.LearnRegularized(); err != nil {
-				return nil
-			}
-			lr.Wn = lr.WReg
-		} else {
-			if err := lr.Learn(); err != nil {
-				return nil
-			}
-		}
-	} else if svm, ok := m.(*svm.SVM); ok {
-		svm.InitializeFromData(fd)
-		if mi.TransformDimension > NOT {
-			svm.ApplyTransformation()
-		}
-		if err := lr.Learn(); err != nil {
-			return nil
+// importedModel returns the container of the model described by the model
+// info passed in, with its learned state if it has one. The model is not
+// initialized, see updateModels.
+//
+func importedModel(mi modelInfo) *ml.ModelContainer {
+	f := familyOf(mi.Model)
+	m := f.newModel(mi)
+	if f.learned != nil && len(mi.State) > 0 {
+		if err := f.unmarshal(m, mi, mi.State); err != nil {
+			log.Printf("unable to restore the state of model %v, %v", mi.name(), err)
 		}
 	}
-	return &m
+	mc := ml.NewModelContainer(m, mi.name(), mi.Features)
+	mc.TransformDimension = int(mi.TransformDimension)
+	mc.TransformID = mi.TransformID
+	if mi.Threshold != nil {
+		thresholds[mc] = *mi.Threshold
+	}
+	return mc
 }
 
 func importModels(path string) (models ml.ModelContainers) {
 	if *verbose {
 		fmt.Printf("importing models from %v\n", path)
 	}
-	var b []byte
-	var err error
-	if b, err = ioutil.ReadFile(path); err != nil {
-		log.Printf("unable to read file %v, %v", path, err)
-		return
-	}
 
-	var modelInfos []modelInfo
-	if err = json.Unmarshal(b, &modelInfos); err != nil {
-		log.Printf("unable to unmarshal bytes %v", err)
+	modelInfos, err := readModelInfos(path)
+	if err != nil {
+		log.Println(err)
 		return
 	}
 
 	for _, mi := range modelInfos {
-		m := mi.newModel()
-		mc := ml.NewModelContainer(m, mi.name(), mi.Features)
-		mc.TransformDimension = int(mi.TransformDimension)
-		mc.TransformID = mi.TransformID
-		models = append(models, mc)
+		// models are trained again with the weighting of the flags.
+		if w := weighted(mi); w.ClassWeight != mi.ClassWeight || w.SampleWeights != mi.SampleWeights {
+			log.Printf("model %v was trained with class weight %q and sample weights %q, training it with class weight %q and sample weights %q",
+				mi.name(), mi.ClassWeight, mi.SampleWeights, w.ClassWeight, w.SampleWeights)
+			mi.State = nil
+		}
+		models = append(models, importedModel(mi))
 	}
 	if *verbose {
 		fmt.Printf("Done importing %v models from %v\n", len(models), path)
@@ -276,22 +279,37 @@ func exportModels(models ml.ModelContainers, path string) {
 		return
 	}
 
+	path = partialName(path)
+
 	if *verbose {
 		fmt.Printf("exporting models to %v\n", path)
 	}
 
 	var modelInfos []modelInfo
+	var err error
 
 	for m := range models {
 		if models[m] == nil {
 			continue
 		}
 
-		mi := ModelInfoFromModel(models[m])
+		f := familyOfModel(models[m].Model)
+		if f == nil {
+			log.Printf("model %v has no model family and cannot be exported", models[m].Name)
+			continue
+		}
+		mi := weighted(ModelInfoFromModel(models[m]))
+		if f.learned != nil && f.learned(models[m].Model) {
+			if mi.State, err = f.marshal(models[m].Model); err != nil {
+				log.Printf("unable to marshal the state of model %v, %v", models[m].Name, err)
+			}
+		}
+		if t, ok := thresholds[models[m]]; ok {
+			mi.Threshold = &t
+		}
 		modelInfos = append(modelInfos, mi)
 	}
 	var b []byte
-	var err error
 
 	if b, err = json.MarshalIndent(modelInfos, "", "    "); err != nil {
 		log.Printf("unable to marshal array of modelInfo objects %v ", err)
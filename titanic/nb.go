@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/nb"
+	"github.com/santiaago/ml"
+)
+
+// categoricalColumns holds the passenger columns with a few discrete values.
+// Naive Bayes models use categorical likelihoods for them and Gaussian
+// likelihoods for the other columns, like Age and Fare.
+// Ticket and Cabin are not categorical: they are identifiers with almost
+// a value per passenger, that a categorical likelihood would learn by heart.
+var categoricalColumns = map[int]bool{
+	passengerIndexPclass:   true,
+	passengerIndexSex:      true,
+	passengerIndexSibSp:    true,
+	passengerIndexParch:    true,
+	passengerIndexEmbarked: true,
+}
+
+// nbState is the learned state of a naive Bayes classifier.
+type nbState struct {
+	Prior    [2]float64
+	Features []nb.Likelihood
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   naiveBayes,
+		name:    "nb",
+		enabled: trainBayes,
+		params: []hyperparameter{
+			{"smoothing", "additive smoothing of the categorical likelihoods."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*nb.NaiveBayes)
+			return ok
+		},
+		hyperparameters: nbHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			b := nb.NewNaiveBayes()
+			b.TransformFunction, b.HasTransform = mi.transformFunction()
+			b.Folds = *folds
+			b.Smoothing = mi.floatParam("smoothing", b.Smoothing)
+			// transformed coordinates mix columns, their kind is detected.
+			if !b.HasTransform {
+				b.Categorical = []bool{false}
+				for _, f := range mi.Features {
+					b.Categorical = append(b.Categorical, categoricalColumns[f])
+				}
+			}
+			return b
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			b := m.(*nb.NaiveBayes)
+			if err := b.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if b.HasTransform {
+				return b.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*nb.NaiveBayes).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*nb.NaiveBayes).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*nb.NaiveBayes).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			n := m.(*nb.NaiveBayes)
+			return classify.Scores(n.Probability, &n.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			mi.setParam("smoothing", m.(*nb.NaiveBayes).Smoothing)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			b := m.(*nb.NaiveBayes)
+			return json.Marshal(nbState{b.Prior, b.Features})
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			b := m.(*nb.NaiveBayes)
+			var s nbState
+			if err := json.Unmarshal(state, &s); err != nil {
+				return err
+			}
+			b.ResetErrors()
+			b.Prior, b.Features = s.Prior, s.Features
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*nb.NaiveBayes).Features) > 0
+		},
+	})
+}
+
+// nbHyperparameters returns a model info for every value of the
+// nbSmoothing flag.
+//
+func nbHyperparameters() (mis []modelInfo) {
+	smoothings, err := floatList(*nbSmoothing)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, s := range smoothings {
+		mi := modelInfo{Model: naiveBayes}
+		mi.setParam("smoothing", s)
+		mis = append(mis, mi)
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// intRange returns the values described by the range passed in.
+// The range is a value 'n', 'from:to' or 'from:to:step', both ends included.
+func intRange(r string) (values []int, err error) {
+	bounds := strings.Split(r, ":")
+	if len(bounds) > 3 {
+		return nil, fmt.Errorf("invalid range %v", r)
+	}
+	v := []int{0, 0, 1}
+	for i, b := range bounds {
+		if v[i], err = strconv.Atoi(strings.TrimSpace(b)); err != nil {
+			return nil, fmt.Errorf("invalid range %v, %v", r, err)
+		}
+	}
+	from, to, step := v[0], v[1], v[2]
+	if len(bounds) == 1 {
+		to = from
+	}
+	if step < 1 || to < from {
+		return nil, fmt.Errorf("invalid range %v", r)
+	}
+	for x := from; x <= to; x += step {
+		values = append(values, x)
+	}
+	return
+}
+
+// floatList returns the values of the comma separated list passed in.
+func floatList(l string) (values []float64, err error) {
+	for _, s := range strings.Split(l, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list %v, %v", l, err)
+		}
+		values = append(values, v)
+	}
+	return
+}
+
+// stringList returns the values of the comma separated list passed in,
+// checking that each of them is one of the valid values.
+func stringList(l string, valid ...string) (values []string, err error) {
+	for _, s := range strings.Split(l, ",") {
+		s = strings.TrimSpace(s)
+		ok := false
+		for _, v := range valid {
+			ok = ok || s == v
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid value %v, expected one of %v", s, valid)
+		}
+		values = append(values, s)
+	}
+	return
+}
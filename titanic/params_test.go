@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntRange(t *testing.T) {
+	tests := []struct {
+		r    string
+		want []int
+		fail bool
+	}{
+		{"3", []int{3}, false},
+		{"-2:2", []int{-2, -1, 0, 1, 2}, false},
+		{"-5:5:5", []int{-5, 0, 5}, false},
+		{"1:4:2", []int{1, 3}, false},
+		{"3:3", []int{3}, false},
+		{"2:1", nil, true},
+		{"1:3:0", nil, true},
+		{"1:2:3:4", nil, true},
+		{"a:2", nil, true},
+	}
+	for _, tt := range tests {
+		values, err := intRange(tt.r)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("intRange(%v) = %v, want an error", tt.r, values)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(values, tt.want) {
+			t.Errorf("intRange(%v) = %v, %v, want %v", tt.r, values, err, tt.want)
+		}
+	}
+}
@@ -38,8 +38,15 @@ const (
 	passengerIndexFare
 	passengerIndexCabin
 	passengerIndexEmbarked
+	passengerIndexWeight // sample weight of the passenger, not a feature, see sampleWeight.
 )
 
+// passengerColumns holds the names of the passenger columns by index.
+var passengerColumns = []string{
+	"PassengerId", "Survived", "Pclass", "Name", "Sex", "Age",
+	"SibSp", "Parch", "Ticket", "Fare", "Cabin", "Embarked", "Weight",
+}
+
 // passengerFeatures return an array of indexes of the
 // passenger colomns data can be used to learn.
 func passengerFeatures() []int {
@@ -169,6 +176,7 @@ func prepareData(passengers []passenger) (data [][]float64) {
 			fare,
 			0,
 			embarked,
+			sampleWeight(p.ID),
 		}
 		data = append(data, d)
 	}
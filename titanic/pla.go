@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/pla"
+	"github.com/santiaago/ml"
+)
+
+// plaState is the learned state of a perceptron.
+type plaState struct {
+	Wn         []float64
+	Converged  bool
+	Iterations int
+}
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   perceptron,
+		name:    "pla",
+		enabled: trainPla,
+		params: []hyperparameter{
+			{"algorithm", "pla keeps the last weights, pocket the weights with the lowest in sample error."},
+			{"iterations", "maximum number of updates of the weights."},
+			{"seed", "seed of the choice of the misclassified point of each update."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*pla.Perceptron)
+			return ok
+		},
+		hyperparameters: plaHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			p := pla.NewPerceptron()
+			p.TransformFunction, p.HasTransform = mi.transformFunction()
+			p.Folds = *folds
+			p.Algorithm = mi.param("algorithm", p.Algorithm)
+			p.MaxIterations = mi.intParam("iterations", p.MaxIterations)
+			p.Seed = int64(mi.intParam("seed", int(p.Seed)))
+			return p
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			p := m.(*pla.Perceptron)
+			if err := p.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if p.HasTransform {
+				return p.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*pla.Perceptron).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			p := m.(*pla.Perceptron)
+			if err := p.Learn(); err != nil {
+				return err
+			}
+			if *verbose && p.Converged {
+				log.Printf("pla converged in %v iterations, the training points are linearly separable", p.Iterations)
+			}
+			return nil
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*pla.Perceptron).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			p := m.(*pla.Perceptron)
+			return classify.Scores(p.Score, &p.Data, x)
+		},
+		describe: func(m ml.Model, mi *modelInfo) {
+			p := m.(*pla.Perceptron)
+			mi.setParam("algorithm", p.Algorithm)
+			mi.setParam("iterations", p.MaxIterations)
+			mi.setParam("seed", p.Seed)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			p := m.(*pla.Perceptron)
+			return json.Marshal(plaState{p.Wn, p.Converged, p.Iterations})
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			p := m.(*pla.Perceptron)
+			var s plaState
+			if err := json.Unmarshal(state, &s); err != nil {
+				return err
+			}
+			p.ResetErrors()
+			p.Wn, p.Converged, p.Iterations = s.Wn, s.Converged, s.Iterations
+			return nil
+		},
+		learned: func(m ml.Model) bool {
+			return len(m.(*pla.Perceptron).Wn) > 0
+		},
+	})
+}
+
+// plaHyperparameters returns a model info for every combination of the
+// values of the plaAlgorithm and plaIterations flags.
+//
+func plaHyperparameters() (mis []modelInfo) {
+	algorithms, err := stringList(*plaAlgorithm, pla.PLA, pla.Pocket)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	iterations, err := intRange(*plaIterations)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, a := range algorithms {
+		for _, t := range iterations {
+			mi := modelInfo{Model: perceptron}
+			mi.setParam("algorithm", a)
+			mi.setParam("iterations", t)
+			mi.setParam("seed", *plaSeed)
+			mis = append(mis, mi)
+		}
+	}
+	return
+}
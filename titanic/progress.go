@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// progress keeps track of the model fits of a training run and reports
+// how many are done out of the planned total, the throughput, the
+// estimated time left and the best model so far.
+type progress struct {
+	sync.Mutex
+	quiet    bool
+	start    time.Time
+	last     time.Time      // time of the last report.
+	planned  map[string]int // planned fits by stage.
+	stage    string         // current stage.
+	total    int            // planned fits of all stages.
+	done     int            // fits done, trained, restored or failed.
+	restored int            // fits restored from a checkpoint.
+	inStage  int            // fits done in the current stage.
+	width    int            // length of the last progress line.
+	byEcv    bool           // the best model has the lowest Ecv instead of the lowest Ein, as in the rankings.
+	best     *ml.ModelContainer
+	bestErr  float64 // Ein or Ecv of the best model.
+}
+
+// fits is the progress of the current run, see startProgress.
+var fits *progress
+
+// startProgress sets up the progress of the current run with the
+// number of fits planned for each stage of the training.
+func startProgress(dc data.Container) {
+	fits = &progress{
+		quiet:   *quiet,
+		start:   time.Now(),
+		byEcv:   *rankEcv,
+		planned: make(map[string]int),
+	}
+	for _, f := range families {
+		if *f.enabled {
+			fits.planFamily(f, dc)
+		}
+	}
+	if *verbose {
+		fmt.Printf("planned %v fits\n", fits.total)
+	}
+}
+
+// planFamily plans the fits of every training stage of a model family.
+// The number of feature selection models is not known in advance, so
+// the worst case of a forward selection is planned and corrected when
+// the stage ends.
+func (p *progress) planFamily(f *modelFamily, dc data.Container) {
+	family := f.name
+	ks := len(f.hyperparameters())
+	n := len(dc.Features)
+	models := 0
+	if *trainSpecific {
+		p.plan(family+" specific", len(specificFeatures))
+		models += len(specificFeatures)
+	}
+	if combinationsEnabled() {
+		if combs, err := featureCombinations(dc.Features, *combinations); err == nil {
+			p.plan(family+" combinations", len(combs)*ks)
+			models += len(combs) * ks
+		}
+	}
+	if *featureSelection != "" {
+		p.plan(family+" selection", n*(n+1)/2)
+		models += n
+	}
+	if *trainTransforms {
+		t := models * len(transformArray(*transformDimension))
+		p.plan(family+" transforms", t)
+		models += t
+	}
+	if *trainRegularized && f.regularizable {
+		p.plan(family+" regularized", models)
+	}
+}
+
+// plan sets the number of fits planned for the stage passed in.
+func (p *progress) plan(stage string, n int) {
+	p.total += n - p.planned[stage]
+	p.planned[stage] = n
+}
+
+// begin starts a stage of n fits.
+// It replaces the number of fits planned for the stage, as it is exact
+// once the models of the previous stages are trained.
+func (p *progress) begin(stage string, n int) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.plan(stage, n)
+	p.stage = stage
+	p.inStage = 0
+	p.report(true)
+}
+
+// end ends the current stage.
+// The fits planned and not done, as in a feature selection that stops
+// early, are removed from the total.
+func (p *progress) end() {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	if !interrupted() {
+		p.plan(p.stage, p.inStage)
+	}
+	if p.quiet {
+		fmt.Printf("%v: %v\n", p.stage, p.status())
+		return
+	}
+	p.report(true)
+	fmt.Println()
+}
+
+// fit records a fit of the current stage.
+// mc is nil if the fit failed.
+func (p *progress) fit(mc *ml.ModelContainer, restored bool) {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.done++
+	p.inStage++
+	if restored {
+		p.restored++
+	}
+	if mc != nil {
+		if e := p.rankError(mc); p.best == nil || e < p.bestErr {
+			p.best = mc
+			p.bestErr = e
+		}
+	}
+	p.report(false)
+}
+
+// rankError returns the error the best model is chosen by for the model
+// passed in: its Ecv if the models are ranked by cross validation error,
+// else its Ein.
+func (p *progress) rankError(mc *ml.ModelContainer) float64 {
+	if p.byEcv {
+		return mc.Model.Ecv()
+	}
+	return mc.Model.Ein()
+}
+
+// metric returns the name of the error the best model is chosen by.
+func (p *progress) metric() string {
+	if p.byEcv {
+		return "Ecv"
+	}
+	return "Ein"
+}
+
+// report prints the progress on a single line, at most every tenth of
+// a second unless force is set.
+// The caller must hold the lock.
+func (p *progress) report(force bool) {
+	if p.quiet || (!force && time.Since(p.last) < 100*time.Millisecond) {
+		return
+	}
+	p.last = time.Now()
+	line := fmt.Sprintf("%v: %v", p.stage, p.status())
+	// pad the line to erase the end of a longer previous line.
+	fmt.Printf("\r%-*v", p.width, line)
+	p.width = len(line)
+}
+
+// status returns the fits done out of the total, the number of fits
+// per second, the estimated time left and the best model so far.
+// Restored fits count as done but not in the fits per second.
+// The caller must hold the lock.
+func (p *progress) status() string {
+	elapsed := time.Since(p.start)
+	rate := float64(p.done-p.restored) / elapsed.Seconds()
+	s := fmt.Sprintf("%v/%v fits", p.done, p.total)
+	if p.total > 0 {
+		s += fmt.Sprintf(" (%.0f%%)", 100*float64(p.done)/float64(p.total))
+	}
+	s += fmt.Sprintf(" %.1f fits/s", rate)
+	if left := p.total - p.done; left > 0 && rate > 0 {
+		eta := time.Duration(float64(left) / rate * float64(time.Second))
+		s += fmt.Sprintf(" ETA %v", eta.Round(time.Second))
+	}
+	if p.best != nil {
+		s += fmt.Sprintf(" best %v %f %v", p.metric(), p.bestErr, p.best.Name)
+	}
+	return s
+}
+
+// finish prints a summary of the run.
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	fmt.Printf("done in %v, %v restored: %v\n", time.Since(p.start).Round(time.Second), p.restored, p.status())
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// regularizationPoint is the cross validation error of a model
+// regularized with lambda = 10^-k.
+type regularizationPoint struct {
+	K      int
+	Lambda float64
+	Ecv    float64
+}
+
+// regularizationCurve holds the cross validation error of a model
+// for every k tried during a regularization sweep.
+type regularizationCurve struct {
+	Name   string
+	Points []regularizationPoint
+}
+
+// regularizations are the regularization curves of the models trained
+// by the current run by model family.
+var regularizations = make(map[string][]regularizationCurve)
+
+// writeRegularizations writes the regularization curves of each model
+// family to regularization.<family>.md in the temp folder.
+// It is called once the training is over, so that the files of an
+// interrupted training are marked as partial.
+func writeRegularizations() {
+	for _, f := range families {
+		writeRegularizationCurves(regularizations[f.name], "regularization."+f.name+".md")
+	}
+	regularizations = make(map[string][]regularizationCurve)
+}
+
+// best returns the point of the curve with the lowest cross validation error.
+func (c regularizationCurve) best() regularizationPoint {
+	ecvs := make([]float64, len(c.Points))
+	for i, p := range c.Points {
+		ecvs[i] = p.Ecv
+	}
+	return c.Points[argmin(ecvs)]
+}
+
+// regularizationSweep returns the regularization curve of a model.
+// For each k it computes the cross validation error on fd, weighted by w,
+// of the models trained by the learner returned by learnK.
+// Values of k that fail to train are left out of the curve.
+// The sweep stops with the error of ctx when ctx is done.
+func regularizationSweep(ctx context.Context, name string, fd [][]float64, w []float64, ks []int, learnK func(k int) learner) (regularizationCurve, error) {
+	curve := regularizationCurve{Name: name}
+	for _, k := range ks {
+		if err := ctx.Err(); err != nil {
+			return curve, err
+		}
+		ecv, err := crossValidationError(fd, w, *folds, learnK(k))
+		if err != nil {
+			if *verbose {
+				fmt.Printf("\tunable to regularize %v with k %v, %v\n", name, k, err)
+			}
+			continue
+		}
+		if *verbose {
+			fmt.Printf("\r\tregularizing %v k %v Ecv %f", name, k, ecv)
+		}
+		curve.Points = append(curve.Points, regularizationPoint{k, math.Pow(10, float64(-k)), ecv})
+	}
+	if len(curve.Points) == 0 {
+		return curve, fmt.Errorf("no regularized model could be trained for %v", name)
+	}
+	return curve, nil
+}
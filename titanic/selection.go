@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// feature selection strategies of the select flag.
+const (
+	forwardSelection  = "forward"
+	backwardSelection = "backward"
+	floatingSelection = "floating"
+)
+
+// checkSelection returns an error if the select flag is not one
+// of the feature selection strategies.
+func checkSelection() error {
+	strategies, err := stringList(*featureSelection, forwardSelection, backwardSelection, floatingSelection)
+	if err != nil {
+		return err
+	}
+	if len(strategies) > 1 {
+		return fmt.Errorf("invalid feature selection %v, expected a single strategy", *featureSelection)
+	}
+	return nil
+}
+
+// featureTrainer trains a model on the data container passed in
+// using only the features passed in.
+type featureTrainer func(dc data.Container, features []int) (*ml.ModelContainer, error)
+
+// featureSelector keeps track of the models trained during a stepwise
+// feature selection so that a feature set is never trained twice.
+type featureSelector struct {
+	dc      data.Container
+	train   featureTrainer
+	trained map[string]*ml.ModelContainer
+}
+
+// newFeatureSelector returns a featureSelector that trains models on dc
+// with the featureTrainer passed in.
+func newFeatureSelector(dc data.Container, train featureTrainer) *featureSelector {
+	return &featureSelector{dc, train, make(map[string]*ml.ModelContainer)}
+}
+
+// model returns the model trained on the features passed in or nil
+// if the model could not be trained.
+func (fs *featureSelector) model(features []int) *ml.ModelContainer {
+	key := fmt.Sprint(features)
+	if mc, ok := fs.trained[key]; ok {
+		return mc
+	}
+	mc, err := fs.train(fs.dc, features)
+	if err != nil {
+		if *verbose {
+			fmt.Printf("\tunable to train model with features %v, %v\n", features, err)
+		}
+		mc = nil
+	}
+	fs.trained[key] = mc
+	return mc
+}
+
+// bestOf returns the model with the lowest cross validation error
+// among the feature sets passed in, and its index.
+// It returns a nil model if none of the feature sets could be trained.
+func (fs *featureSelector) bestOf(candidates [][]int) (best *ml.ModelContainer, index int) {
+	index = -1
+	for i, c := range candidates {
+		mc := fs.model(c)
+		if mc == nil {
+			continue
+		}
+		if best == nil || mc.Model.Ecv() < best.Model.Ecv() {
+			best = mc
+			index = i
+		}
+	}
+	return
+}
+
+// selectFeatures returns the models found by the feature selection
+// strategy defined in the select flag.
+// Each returned model is the best model found for a given feature set size.
+// family is the name of the model family trained, used to report progress.
+// The select flag is checked by checkSelection before training.
+func selectFeatures(dc data.Container, family string, train featureTrainer) (models ml.ModelContainers) {
+
+	n := len(dc.Features)
+	fits.begin(family+" selection", n*(n+1)/2)
+	defer fits.end()
+
+	// the containers of the trainer can be shared with other stages,
+	// so the selected models are named on a container of their own.
+	fs := newFeatureSelector(dc, func(dc data.Container, features []int) (*ml.ModelContainer, error) {
+		mc, err := train(dc, features)
+		if err != nil {
+			return nil, err
+		}
+		selected := *mc
+		selected.Name += " " + *featureSelection
+		return &selected, nil
+	})
+
+	switch *featureSelection {
+	case forwardSelection:
+		models = fs.forward()
+	case backwardSelection:
+		models = fs.backward()
+	case floatingSelection:
+		models = fs.floating()
+	}
+	return
+}
+
+// forward returns the models found by greedy forward selection.
+// It starts with no features and adds, one column at a time,
+// the feature that gives the lowest cross validation error.
+// It stops when adding a feature does not improve the cross validation error.
+func (fs *featureSelector) forward() (models ml.ModelContainers) {
+
+	var selected []int
+	var best *ml.ModelContainer
+
+	for len(selected) < len(fs.dc.Features) {
+		m, _ := fs.bestOf(additions(selected, fs.dc.Features))
+		if m == nil || (best != nil && m.Model.Ecv() >= best.Model.Ecv()) {
+			break
+		}
+		best = m
+		selected = m.Features
+		models = append(models, m)
+		if *verbose {
+			fmt.Printf("\tforward selection Ecv = %f %v\n", m.Model.Ecv(), selected)
+		}
+	}
+	return
+}
+
+// backward returns the models found by greedy backward elimination.
+// It starts with all the features and removes, one column at a time,
+// the feature whose removal gives the lowest cross validation error.
+// It stops when removing a feature does not improve the cross validation error.
+func (fs *featureSelector) backward() (models ml.ModelContainers) {
+
+	selected := sortedCopy(fs.dc.Features)
+	best := fs.model(selected)
+	if best == nil {
+		return
+	}
+	models = append(models, best)
+
+	for len(selected) > 1 {
+		m, _ := fs.bestOf(removals(selected))
+		if m == nil || m.Model.Ecv() >= best.Model.Ecv() {
+			break
+		}
+		best = m
+		selected = m.Features
+		models = append(models, m)
+		if *verbose {
+			fmt.Printf("\tbackward elimination Ecv = %f %v\n", m.Model.Ecv(), selected)
+		}
+	}
+	return
+}
+
+// floating returns the models found by sequential floating forward selection.
+// After each forward step it removes features, one at a time, for as long as
+// the removal gives a lower cross validation error than the best model
+// previously found with the same number of features.
+// It returns the best model found for each feature set size.
+func (fs *featureSelector) floating() (models ml.ModelContainers) {
+
+	bestBySize := make(map[int]*ml.ModelContainer)
+	better := func(m *ml.ModelContainer) bool {
+		b, ok := bestBySize[len(m.Features)]
+		return !ok || m.Model.Ecv() < b.Model.Ecv()
+	}
+
+	var selected []int
+	for len(selected) < len(fs.dc.Features) {
+		m, _ := fs.bestOf(additions(selected, fs.dc.Features))
+		if m == nil {
+			break
+		}
+		selected = m.Features
+		if better(m) {
+			bestBySize[len(selected)] = m
+		}
+		if *verbose {
+			fmt.Printf("\tfloating selection add Ecv = %f %v\n", m.Model.Ecv(), selected)
+		}
+
+		for len(selected) > 2 {
+			r, _ := fs.bestOf(removals(selected))
+			if r == nil || !better(r) {
+				break
+			}
+			selected = r.Features
+			bestBySize[len(selected)] = r
+			if *verbose {
+				fmt.Printf("\tfloating selection remove Ecv = %f %v\n", r.Model.Ecv(), selected)
+			}
+		}
+	}
+
+	var sizes []int
+	for size := range bestBySize {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+	for _, size := range sizes {
+		models = append(models, bestBySize[size])
+	}
+	return
+}
+
+// additions returns all the feature sets made of the selected features
+// plus one of the candidate features not yet selected.
+func additions(selected, candidates []int) (sets [][]int) {
+	in := make(map[int]bool)
+	for _, f := range selected {
+		in[f] = true
+	}
+	for _, c := range candidates {
+		if in[c] {
+			continue
+		}
+		sets = append(sets, sortedCopy(append(sortedCopy(selected), c)))
+	}
+	return
+}
+
+// removals returns all the feature sets made of the selected features
+// minus one of them.
+func removals(selected []int) (sets [][]int) {
+	for i := range selected {
+		var s []int
+		s = append(s, selected[:i]...)
+		s = append(s, selected[i+1:]...)
+		sets = append(sets, s)
+	}
+	return
+}
+
+// sortedCopy returns a sorted copy of the array passed in.
+func sortedCopy(a []int) []int {
+	c := make([]int, len(a))
+	copy(c, a)
+	sort.Ints(c)
+	return c
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdditions(t *testing.T) {
+	tests := []struct {
+		selected, candidates []int
+		want                 [][]int
+	}{
+		{nil, []int{2, 4}, [][]int{{2}, {4}}},
+		{[]int{4}, []int{2, 4, 6}, [][]int{{2, 4}, {4, 6}}},
+		{[]int{6, 2}, []int{4, 2}, [][]int{{2, 4, 6}}},
+		{[]int{2, 4}, []int{2, 4}, nil},
+	}
+	for _, tt := range tests {
+		if got := additions(tt.selected, tt.candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("additions(%v, %v) = %v, want %v", tt.selected, tt.candidates, got, tt.want)
+		}
+	}
+}
+
+func TestAdditionsKeepSelected(t *testing.T) {
+	selected := []int{6, 2}
+	additions(selected, []int{4})
+	if !reflect.DeepEqual(selected, []int{6, 2}) {
+		t.Errorf("additions changed the selected features to %v", selected)
+	}
+}
+
+func TestRemovals(t *testing.T) {
+	tests := []struct {
+		selected []int
+		want     [][]int
+	}{
+		{nil, nil},
+		{[]int{2}, [][]int{{}}},
+		{[]int{2, 4, 6}, [][]int{{4, 6}, {2, 6}, {2, 4}}},
+	}
+	for _, tt := range tests {
+		got := removals(tt.selected)
+		if len(got) != len(tt.want) {
+			t.Errorf("removals(%v) = %v, want %v", tt.selected, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if len(got[i]) != len(tt.want[i]) || (len(got[i]) > 0 && !reflect.DeepEqual(got[i], tt.want[i])) {
+				t.Errorf("removals(%v) = %v, want %v", tt.selected, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCheckSelection(t *testing.T) {
+	defer func(s string) { *featureSelection = s }(*featureSelection)
+	tests := []struct {
+		strategy string
+		valid    bool
+	}{
+		{"forward", true},
+		{"floating", true},
+		{"stepwise", false},
+		{"forward,backward", false},
+	}
+	for _, tt := range tests {
+		*featureSelection = tt.strategy
+		if err := checkSelection(); (err == nil) != tt.valid {
+			t.Errorf("checkSelection() with -select %v returned %v", tt.strategy, err)
+		}
+	}
+}
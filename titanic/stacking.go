@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+	"github.com/santiaago/ml/logreg"
+)
+
+// stacking is an ensemble whose members are combined by a logistic
+// regression, the meta learner, trained on the out of fold predictions of
+// the members: the prediction of each training point by the members
+// trained on the other folds.
+// The members used to predict are the ones trained on the whole data.
+type stacking struct {
+	members    ml.ModelContainers // trained members.
+	thresholds []*float64         // decision threshold of each member, see memberThresholds.
+	features   []int              // sorted union of the features of the members.
+	dc         data.Container     // training data of the members.
+	oof        [][]float64        // out of fold predictions of each member, the label last.
+	meta       *logreg.LogisticRegression
+	ein, ecv   float64
+}
+
+// newStacking returns an untrained stacking of the members passed in,
+// trained on dc.
+func newStacking(members ml.ModelContainers, dc data.Container) *stacking {
+	return &stacking{members: members, thresholds: memberThresholds(members), features: unionFeatures(members), dc: dc}
+}
+
+// Learn computes the out of fold predictions of the members on the same
+// folds as the cross validation of the models, see classify.Folds, and
+// trains the meta learner on them.
+// The cross validation error is the one of the meta learner on the out of
+// fold predictions, with the same folds. As for the other ensembles, it is
+// optimistic when the members have decision thresholds, see ensemble.Learn.
+func (s *stacking) Learn() error {
+	s.oof = make([][]float64, len(s.dc.Data))
+	for _, fold := range classify.Folds(len(s.dc.Data), *folds) {
+		train, validation := splitContainer(s.dc, fold)
+		members, err := refitMembers(s.members, train)
+		if err != nil {
+			return err
+		}
+		votes, err := s.votes(members, validation.Filter(s.features))
+		if err != nil {
+			return err
+		}
+		for r, i := range fold {
+			s.oof[i] = append(votes[r], s.dc.Data[i][s.dc.Predict])
+		}
+	}
+
+	var err error
+	if s.meta, err = metaLearner(s.oof); err != nil {
+		return err
+	}
+
+	predictions, err := s.Predictions(s.dc.Filter(s.features))
+	if err != nil {
+		return err
+	}
+	s.ein = wrongPredictions(s.dc.Data, s.dc.Predict, predictions) / float64(len(s.dc.Data))
+
+	s.ecv, err = crossValidationError(s.oof, nil, *folds, func(fd [][]float64, w []float64) (ml.Model, error) {
+		return metaLearner(fd)
+	})
+	return err
+}
+
+// metaLearner returns a logistic regression trained on the out of fold
+// predictions passed in.
+func metaLearner(oof [][]float64) (*logreg.LogisticRegression, error) {
+	meta := logreg.NewLogisticRegression()
+	if err := meta.InitializeFromData(oof); err != nil {
+		return nil, err
+	}
+	if err := meta.Learn(); err != nil {
+		return nil, fmt.Errorf("unable to train the meta learner, %v", err)
+	}
+	return meta, nil
+}
+
+// votes returns, for each point of x, the predictions of the members
+// passed in, the members of the stacking or the same members trained on
+// a fold.
+func (s *stacking) votes(members ml.ModelContainers, x [][]float64) ([][]float64, error) {
+	votes := make([][]float64, len(x))
+	for r := range votes {
+		// room for the label of the out of fold predictions.
+		votes[r] = make([]float64, len(members), len(members)+1)
+	}
+	for j, m := range members {
+		predictions, err := memberPredictions(m, s.thresholds[j], s.features, x)
+		if err != nil {
+			return nil, err
+		}
+		for r, p := range predictions {
+			votes[r][j] = p
+		}
+	}
+	return votes, nil
+}
+
+// Ein returns the in sample error.
+func (s *stacking) Ein() float64 {
+	return s.ein
+}
+
+// Ecv returns the cross validation error.
+func (s *stacking) Ecv() float64 {
+	return s.ecv
+}
+
+// Predictions returns the predictions of the points passed in, without
+// labels, with the columns of the features of the stacking.
+func (s *stacking) Predictions(x [][]float64) ([]float64, error) {
+	votes, err := s.votes(s.members, x)
+	if err != nil {
+		return nil, err
+	}
+	return s.meta.Predictions(votes)
+}
+
+// writeOutOfFold writes the out of fold predictions of the stacking passed
+// in to the file name in the temp folder, a row per training point and a
+// column per member, named after it, followed by the label.
+//
+func writeOutOfFold(s *stacking, name string) error {
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + name)
+	if err != nil {
+		return fmt.Errorf("unable to create out of fold predictions file %v, %v", *tempPath+name, err)
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	header := make([]string, 0, len(s.members)+1)
+	for _, m := range s.members {
+		header = append(header, m.Name)
+	}
+	records := [][]string{append(header, "Survived")}
+	for _, row := range s.oof {
+		record := make([]string, len(row))
+		for j, v := range row {
+			record[j] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		records = append(records, record)
+	}
+	if err = w.WriteAll(records); err != nil {
+		return fmt.Errorf("unable to write out of fold predictions file %v, %v", *tempPath+name, err)
+	}
+	return nil
+}
@@ -1,126 +1,121 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 
-	"github.com/santiaago/kaggle/itertools"
-
 	"github.com/santiaago/ml"
-	"github.com/santiaago/ml/data"
 	"github.com/santiaago/ml/svm"
 )
 
-// svmTest sets the Survived field of each passenger in the passenger array
-// with respect to the prediction set by the svm 'svm' passed as argument.
-//
-func svmTest(model *ml.ModelContainer, dc data.Container) ([]float64, error) {
-	if *verbose {
-		fmt.Printf("\ttesting svm model:%v\n", model.Name)
-		fmt.Printf("\t%+v\n", model)
-	}
-	fd := dc.Filter(model.Features)
-	svm, ok := model.Model.(*svm.SVM)
-	if !ok {
-		return nil, fmt.Errorf("not an SVM")
-	}
-	return svm.Predictions(fd)
-}
-
-// svmCombinations creates an svm model for each combination of
-// the feature vector with respect to the size param.
-// It returns an array of svm, one for each combination.
-// todo(santiaago): move to ml
-//
-func svmCombinations(dc data.Container, size int) (models ml.ModelContainers) {
-	if *verbose {
-		fmt.Printf("\truning svm %v combinations\n", size)
-	}
-	combs := itertools.Combinations(dc.Features, size)
-
-	for _, c := range combs {
-		if *svmK == 1 {
-			for k := 1; k <= *svmKRange; k++ {
-				fmt.Printf("\r%v/%v", c, len(combs))
-				fd := dc.FilterWithPredict(c)
-				svm := svm.NewSVM()
-				svm.K = k
-				svm.Lambda = *svmLambda
-				svm.T = *svmT
-				svm.InitializeFromData(fd)
-
-				name := fmt.Sprintf("svm 1D %v k %v T %v L %v", c, k, *svmT, *svmLambda)
-
-				if err := svm.Learn(); err == nil {
-					models = append(models, ml.NewModelContainer(svm, name, c))
-				}
-			}
-		} else {
-			fmt.Printf("\r%v/%v", c, len(combs))
-			fd := dc.FilterWithPredict(c)
-			svm := svm.NewSVM()
-			svm.K = *svmK
-			svm.Lambda = *svmLambda
-			svm.T = *svmT
-			svm.InitializeFromData(fd)
-
-			name := fmt.Sprintf("svm 1D %v k %v T %v L %v", c, *svmK, *svmT, *svmLambda)
-
-			if err := svm.Learn(); err == nil {
-				models = append(models, ml.NewModelContainer(svm, name, c))
+func init() {
+	registerFamily(&modelFamily{
+		model:   supportVectorMachines,
+		name:    "svm",
+		enabled: trainSvm,
+		is: func(m ml.Model) bool {
+			_, ok := unwrap(m).(*svm.SVM)
+			return ok
+		},
+		hyperparameters: func() (mis []modelInfo) {
+			for _, k := range svmKs() {
+				mis = append(mis, modelInfo{Model: supportVectorMachines, K: k, T: *svmT, L: *svmLambda})
 			}
+			return
+		},
+		newModel: func(mi modelInfo) ml.Model {
+			s := svm.NewSVM()
+			s.TransformFunction, s.HasTransform = mi.transformFunction()
+			s.K = mi.K
+			s.T = mi.T
+			s.Lambda = mi.L
 			if *verbose {
-				fmt.Println("DEBUG")
-				fmt.Printf("SVM features %v\n", c)
-				fmt.Printf("SVM Wn %v\n", svm.Wn)
-				fmt.Printf("SVM Vector Size %v\n", svm.VectorSize)
-				fmt.Printf("SVM Has transform %v\n", svm.HasTransform)
-				fmt.Printf("SVM Training points %v\n", svm.TrainingPoints)
-				fmt.Printf("SVM Lambda %v\n", svm.Lambda)
-				fmt.Printf("SVM Eta %v\n", svm.Eta)
-				fmt.Printf("SVM K %v\n", svm.K)
-				fmt.Printf("SVM T %v\n", svm.T)
+				fmt.Printf("setting svm with k: %v T: %v L: %v\n", mi.K, mi.T, mi.L)
 			}
-		}
-	}
-	fmt.Println()
-	return
-}
-
-func specificSvmModels(dc data.Container) (models ml.ModelContainers) {
-
-	cases := []struct {
-		features []int
-		name     string
-	}{
-		{
-			[]int{passengerIndexSex, passengerIndexAge},
-			"svm Sex Age",
+			return s
 		},
-		{
-			[]int{passengerIndexAge, passengerIndexPclass},
-			"svm PClass Age",
+		initialize: func(m ml.Model, fd [][]float64) error {
+			s := unwrap(m).(*svm.SVM)
+			s.InitializeFromData(fd)
+			if s.HasTransform {
+				return s.ApplyTransformation()
+			}
+			return nil
 		},
-
-		{
-			[]int{passengerIndexSex, passengerIndexPclass},
-			"svm PClass Sex",
+		weigh: func(m ml.Model, w []float64) error {
+			s := unwrap(m).(*svm.SVM)
+			var err error
+			s.Xn, s.Yn, err = resampled(s.Xn, s.Yn, w)
+			return err
 		},
-		{
-			[]int{passengerIndexSex, passengerIndexAge, passengerIndexPclass},
-			"svm Sex Age PClass",
+		resampled: true,
+		learn: func(m ml.Model, mi modelInfo) error {
+			return unwrap(m).(*svm.SVM).Learn()
 		},
-	}
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return unwrap(m).(*svm.SVM).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			s := unwrap(m).(*svm.SVM)
+			return linearScores(s.Wn, s.TransformFunction, s.HasTransform, x)
+		},
+		describe: func(m ml.Model, mi *modelInfo) {
+			s := unwrap(m).(*svm.SVM)
+			mi.K = s.K
+			mi.T = s.T
+			mi.L = s.Lambda
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			return json.Marshal(unwrap(m).(*svm.SVM).Wn)
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			return json.Unmarshal(state, &unwrap(m).(*svm.SVM).Wn)
+		},
+		learned: func(m ml.Model) bool {
+			return len(unwrap(m).(*svm.SVM).Wn) > 0
+		},
+		label: func(mi modelInfo) string {
+			return fmt.Sprintf(" k %v T %v L %v", mi.K, mi.T, mi.L)
+		},
+		override: svmOverride,
+	})
+}
 
-	for _, c := range cases {
-		svm := svm.NewSVM()
-		fd := dc.FilterWithPredict(c.features)
-		svm.InitializeFromData(fd)
+// svmKs returns the block sizes to try for every combination of features:
+// from 1 to svmKRange when svmK is 1, else svmK only.
+//
+func svmKs() []int {
+	if *svmK != 1 {
+		return []int{*svmK}
+	}
+	var ks []int
+	for k := 1; k <= *svmKRange; k++ {
+		ks = append(ks, k)
+	}
+	return ks
+}
 
-		if err := svm.Learn(); err != nil {
-			continue
+// svmOverride returns the model info passed in with the svm parameters
+// replaced by the svmK, svmT and svmL flags when their override flag is set.
+//
+func svmOverride(mi modelInfo) modelInfo {
+	if *svmKOverride {
+		if *verbose {
+			fmt.Printf("\toverriding K %v\n", *svmK)
+		}
+		mi.K = *svmK
+	}
+	if *svmTOverride {
+		if *verbose {
+			fmt.Printf("\toverriding T %v\n", *svmT)
+		}
+		mi.T = *svmT
+	}
+	if *svmLambdaOverride {
+		if *verbose {
+			fmt.Printf("\toverriding L %v\n", *svmLambda)
 		}
-		mc := ml.NewModelContainer(svm, c.name, c.features)
-		models = append(models, mc)
+		mi.L = *svmLambda
 	}
-	return
+	return mi
 }
@@ -6,15 +6,13 @@ import (
 
 	"github.com/santiaago/ml"
 	"github.com/santiaago/ml/data"
-	"github.com/santiaago/ml/linreg"
-	"github.com/santiaago/ml/logreg"
-	"github.com/santiaago/ml/svm"
 )
 
 // testModels runs a model on the data passed in the reader.
 // Then makes the predictions and write the predicted data to file using the
 // model name.
 // testModels run a test file for each model passed in the array.
+// The files are marked as partial if the training was interrupted.
 //
 func testModels(models ml.ModelContainers) {
 	if !*test {
@@ -40,19 +38,21 @@ func testModels(models ml.ModelContainers) {
 		if m == nil {
 			continue
 		}
-		switch m.Model.(type) {
-		case *linreg.LinearRegression:
-			if predictions, err := linregTest(m, dc); err == nil {
-				w.Write(m.Name, predictions)
-			}
-		case *logreg.LogisticRegression:
-			if predictions, err := logregTest(m, dc); err == nil {
-				w.Write(m.Name, predictions)
-			}
-		case *svm.SVM:
-			if predictions, err := svmTest(m, dc); err == nil {
-				w.Write(m.Name, predictions)
-			}
+		// models without a family, like ensembles, predict by themselves.
+		predict := m.Model.Predictions
+		if f := familyOfModel(m.Model); f != nil {
+			predict = func(x [][]float64) ([]float64, error) { return f.predict(m.Model, x) }
+		}
+		if t, ok := thresholds[m]; ok {
+			predict = func(x [][]float64) ([]float64, error) { return thresholdPredictions(m, t, x) }
+		}
+		// model names have no extension but may have dots, see partialName.
+		name := m.Name
+		if partialResults {
+			name += ".partial"
+		}
+		if predictions, err := predict(dc.Filter(m.Features)); err == nil {
+			w.Write(name, predictions)
 		}
 	}
 	if *verbose {
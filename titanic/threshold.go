@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/santiaago/ml"
+)
+
+// Metrics maximized by the decision threshold of a model.
+const (
+	accuracyMetric = "accuracy" // fraction of well classified points.
+	f1Metric       = "f1"       // harmonic mean of the precision and the recall of the label +1.
+	balancedMetric = "balanced" // mean of the recalls of the labels +1 and -1.
+)
+
+// thresholds are the decision thresholds of the models, tuned by
+// thresholdModels or imported with them. A model predicts +1 for the
+// points whose score is above its threshold.
+var thresholds = make(map[*ml.ModelContainer]float64)
+
+// thresholdMetric returns the metric passed in of the predictions of the
+// scores by the threshold passed in.
+func thresholdMetric(metric string, scores, labels []float64, threshold float64) float64 {
+	var tp, fp, tn, fn float64
+	for i, s := range scores {
+		switch {
+		case s > threshold && labels[i] > 0:
+			tp++
+		case s > threshold:
+			fp++
+		case labels[i] > 0:
+			fn++
+		default:
+			tn++
+		}
+	}
+	switch metric {
+	case f1Metric:
+		if tp == 0 {
+			return 0
+		}
+		return 2 * tp / (2*tp + fp + fn)
+	case balancedMetric:
+		var recall, specificity float64
+		if tp+fn > 0 {
+			recall = tp / (tp + fn)
+		}
+		if tn+fp > 0 {
+			specificity = tn / (tn + fp)
+		}
+		return (recall + specificity) / 2
+	}
+	if len(scores) == 0 {
+		return 0
+	}
+	return (tp + tn) / float64(len(scores))
+}
+
+// bestThreshold returns the threshold of the scores that maximizes the
+// metric passed in and its value. The candidates are the midpoints between
+// consecutive distinct scores and the ends; ties are broken by the closest
+// candidate to the default threshold passed in.
+func bestThreshold(metric string, scores, labels []float64, def float64) (threshold, value float64) {
+	sorted := append([]float64{}, scores...)
+	sort.Float64s(sorted)
+	candidates := []float64{def}
+	if len(sorted) > 0 {
+		candidates = append(candidates, sorted[0]-1, sorted[len(sorted)-1])
+	}
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] != sorted[i-1] {
+			candidates = append(candidates, (sorted[i]+sorted[i-1])/2)
+		}
+	}
+	threshold, value = def, thresholdMetric(metric, scores, labels, def)
+	for _, c := range candidates {
+		v := thresholdMetric(metric, scores, labels, c)
+		if v > value || (v == value && math.Abs(c-def) < math.Abs(threshold-def)) {
+			threshold, value = c, v
+		}
+	}
+	return
+}
+
+// defaultThreshold returns the threshold of the scores of the family
+// passed in that gives its usual predictions: one half for probabilities
+// and zero for margins.
+func defaultThreshold(f *modelFamily) float64 {
+	if f.probabilistic {
+		return 0.5
+	}
+	return 0
+}
+
+// tunedThreshold is the decision threshold of a model tuned on its out of
+// fold scores.
+type tunedThreshold struct {
+	Name      string  // name of the model.
+	Default   float64 // threshold of the usual predictions of the model.
+	Threshold float64 // threshold that maximizes the metric.
+	Before    float64 // metric of the out of fold scores by the default threshold.
+	After     float64 // metric of the out of fold scores by the tuned threshold.
+}
+
+// tuneThresholds returns the decision thresholds of the models passed in
+// that maximize the metric passed in on their out of fold scores, computed
+// by oof. Models without a family, like ensembles, are not tuned.
+//
+func tuneThresholds(models ml.ModelContainers, oof *outOfFoldScorer, metric string) (tuned []tunedThreshold) {
+	for _, mc := range models {
+		f := familyOfModel(mc.Model)
+		if f == nil {
+			continue
+		}
+		scores, labels, err := oof.scores(mc)
+		if err != nil {
+			log.Printf("unable to compute the out of fold scores of model %v, %v", mc.Name, err)
+			continue
+		}
+		t := tunedThreshold{Name: mc.Name, Default: defaultThreshold(f)}
+		t.Before = thresholdMetric(metric, scores, labels, t.Default)
+		t.Threshold, t.After = bestThreshold(metric, scores, labels, t.Default)
+		thresholds[mc] = t.Threshold
+		tuned = append(tuned, t)
+	}
+	return
+}
+
+// thresholdModels tunes the decision thresholds of the models passed in,
+// the top ranked models, when the threshold flag is set, writes them to
+// the file name in the temp folder and tests the models again with them.
+// Their out of fold scores are computed by oof.
+//
+func thresholdModels(models ml.ModelContainers, oof *outOfFoldScorer, name string) {
+	if *thresholdBy == "" {
+		return
+	}
+	metrics, err := stringList(*thresholdBy, accuracyMetric, f1Metric, balancedMetric)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if len(metrics) != 1 {
+		log.Printf("invalid threshold metric %v, expected a single metric", *thresholdBy)
+		return
+	}
+	writeThresholds(tuneThresholds(models, oof, metrics[0]), metrics[0], name)
+	testModels(models)
+}
+
+// thresholdPredictions returns the predictions of the model passed in for
+// the points of x by its decision threshold.
+func thresholdPredictions(mc *ml.ModelContainer, threshold float64, x [][]float64) ([]float64, error) {
+	f := familyOfModel(mc.Model)
+	if f == nil {
+		return nil, fmt.Errorf("model %v has no model family", mc.Name)
+	}
+	scores, err := f.scores(mc.Model, x)
+	if err != nil {
+		return nil, err
+	}
+	predictions := make([]float64, len(scores))
+	for i, s := range scores {
+		predictions[i] = -1
+		if s > threshold {
+			predictions[i] = 1
+		}
+	}
+	return predictions, nil
+}
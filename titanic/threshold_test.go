@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestThresholdMetric(t *testing.T) {
+	scores := []float64{0.1, 0.4, 0.6, 0.9}
+	labels := []float64{-1, 1, -1, 1}
+	tests := []struct {
+		metric    string
+		threshold float64
+		want      float64
+	}{
+		{accuracyMetric, 0.5, 0.5},
+		{accuracyMetric, 0.3, 0.75},
+		{accuracyMetric, 1, 0.5},
+		{f1Metric, 0.5, 0.5},
+		{f1Metric, 0.3, 0.8},
+		{f1Metric, 1, 0},
+		{balancedMetric, 0.5, 0.5},
+		{balancedMetric, 0.3, 0.75},
+		{balancedMetric, 1, 0.5},
+	}
+	for _, tt := range tests {
+		if got := thresholdMetric(tt.metric, scores, labels, tt.threshold); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("thresholdMetric(%v, %v) = %v, want %v", tt.metric, tt.threshold, got, tt.want)
+		}
+	}
+	if got := thresholdMetric(accuracyMetric, nil, nil, 0.5); got != 0 {
+		t.Errorf("accuracy of no scores = %v, want 0", got)
+	}
+}
+
+func TestBestThreshold(t *testing.T) {
+	tests := []struct {
+		metric           string
+		scores, labels   []float64
+		def              float64
+		threshold, value float64
+	}{
+		// 0.25 and 0.75 are as accurate, 0.75 is the closest to the default.
+		{accuracyMetric, []float64{0.1, 0.4, 0.6, 0.9}, []float64{-1, 1, -1, 1}, 0.95, 0.75, 0.75},
+		{f1Metric, []float64{0.1, 0.4, 0.6, 0.9}, []float64{-1, 1, -1, 1}, 0.5, 0.25, 0.8},
+		// the default is kept when no candidate is better.
+		{accuracyMetric, []float64{0.2, 0.8}, []float64{-1, 1}, 0.5, 0.5, 1},
+		// margins: every score negative but the labels of the highest ones.
+		{accuracyMetric, []float64{-3, -2, -1, -0.5}, []float64{-1, -1, 1, 1}, 0, -1.5, 1},
+		{balancedMetric, nil, nil, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		threshold, value := bestThreshold(tt.metric, tt.scores, tt.labels, tt.def)
+		if math.Abs(threshold-tt.threshold) > 1e-9 || math.Abs(value-tt.value) > 1e-9 {
+			t.Errorf("bestThreshold(%v, %v, %v) = %v, %v, want %v, %v",
+				tt.metric, tt.scores, tt.def, threshold, value, tt.threshold, tt.value)
+		}
+	}
+}
@@ -1,668 +1,482 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+
+	"github.com/santiaago/kaggle/itertools"
 
 	"github.com/santiaago/ml"
 	"github.com/santiaago/ml/data"
-	"github.com/santiaago/ml/linreg"
-	"github.com/santiaago/ml/logreg"
-	"github.com/santiaago/ml/svm"
 	"github.com/santiaago/ml/transform"
 )
 
 // trainModels returns:
-// * an array of trained LinearRegression/LogisticRegression/svm models.
+// * an array of trained models of every enabled model family.
 // It uses the reader passed as param to read the data.
 // It trains multiple models using different techniques:
-// * trainSpecificModels
-// * trainModelsByFeatrueCombination
-// * trainModelsWithTransform
-// * trainModelsWithRegularization
+// * specificModels
+// * combinationModels
+// * selectFeatures
+// * transformModels
+// * regularizedModels
 //
 func trainModels() (models ml.ModelContainers) {
 	if *verbose {
 		fmt.Println("Starting training models")
 	}
-
-	reader := NewPassengerReader(*trainSrc, NewPassengerTrainExtractor())
-
-	var dc data.Container
-	var err error
-	dc, err = reader.Read()
-	if err != nil {
-		log.Println("error when getting the data.container from the reader,", err)
+	if err := checkWeighting(); err != nil {
+		log.Fatalln(err)
 	}
 
+	dc := trainData()
+
 	if *canImportModels {
 		models = updateModels(dc, importModels(*importPath))
 		return
 	}
 
-	linregModels := trainLinregModels(dc)
-	models = append(models, linregModels...)
-
-	logregModels := trainLogregModels(dc)
-	models = append(models, logregModels...)
-
-	svmModels := trainSvmModels(dc)
-	models = append(models, svmModels...)
-
-	if *verbose {
-		fmt.Printf("Done. Trained %v models\n", len(models))
-	}
-	return
-}
-
-// trainLinregModels returns an array of modelContainers
-// with the trained linear regression models specified to be trained.
-// You specify which models to train by setting the trainLinreg flags.
-//
-func trainLinregModels(dc data.Container) (models ml.ModelContainers) {
-	if !*trainLinreg {
-		return
-	}
-	if *verbose {
-		fmt.Println("training linreg models")
-	}
-	if *trainSpecific {
-		if *verbose {
-			fmt.Println("\n\ttraining specific")
+	if combinationsEnabled() {
+		if _, _, err := combinationSizes(*combinations, len(dc.Features)); err != nil {
+			log.Fatalln(err)
 		}
-
-		s := specificLinregModels(dc)
-		models = append(models, s...)
-
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v specific models\n", len(s))
+	}
+	if *featureSelection != "" {
+		if err := checkSelection(); err != nil {
+			log.Fatalln(err)
 		}
 	}
 
-	if *combinations > 0 {
-		if *verbose {
-			fmt.Println("\n\ttraining combinations")
-		}
+	startCheckpoints(dc)
+	defer checkpoints.flush()
 
-		c := trainLinregModelsByFeatureCombination(dc)
-		models = append(models, c...)
+	handleInterrupts()
+	defer func() {
+		partialResults = interrupted()
+		stopInterrupts()
+		writeRegularizations()
+	}()
 
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v combination models\n", len(c))
-		}
-	}
+	openCache(dc)
+	defer cache.report()
 
-	if *trainTransforms {
-		if *verbose {
-			fmt.Println("\n\ttraining transforms")
-		}
+	startProgress(dc)
+	defer fits.finish()
 
-		t := trainLinregModelsWithTransform(models, dc)
-		models = append(models, t...)
-
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v transformed models\n", len(t))
+	for _, f := range families {
+		if *f.enabled {
+			models = append(models, trainFamilyModels(f, dc)...)
 		}
 	}
 
-	if *trainRegularized {
-		if *verbose {
-			fmt.Println("\n\ttraining regularized models")
-		}
-		r := trainLinregModelsRegularized(models)
-		models = append(models, r...)
-
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v regularized models\n", len(r))
-		}
+	if *verbose {
+		fmt.Printf("Done. Trained %v models\n", len(models))
 	}
 	return
 }
 
-// trainLogregModels returns an array of modelContainers
-// with the trained logistic regression models specified to be trained.
-// You specify which models to train by setting the trainLinreg flags.
+// trainData returns the data container of the passengers of trainSrc.
 //
-func trainLogregModels(dc data.Container) (models ml.ModelContainers) {
-	if !*trainLogreg {
-		return
-	}
-	if *verbose {
-		fmt.Println("training logreg models")
-	}
-
-	if *trainSpecific {
-		if *verbose {
-			fmt.Println("\ttraining specific")
-		}
-		s := specificLogregModels(dc)
-		models = append(models, s...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v specific models\n", len(s))
-		}
-
-	}
-
-	if *combinations > 0 {
-		if *verbose {
-			fmt.Println("\ttraining combinations")
-		}
-		c := trainLogregModelsByFeatureCombination(dc)
-		models = append(models, c...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v combination models\n", len(c))
-		}
-	}
-
-	if *trainTransforms {
-		if *verbose {
-			fmt.Println("\ttraining transforms")
-		}
-		t := trainLogregModelsWithTransform(models, dc)
-		models = append(models, t...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v combination models\n", len(t))
-		}
-	}
+func trainData() data.Container {
+	reader := NewPassengerReader(*trainSrc, NewPassengerTrainExtractor())
 
-	if *trainRegularized {
-		if *verbose {
-			fmt.Println("\ttraining regularized models")
-		}
-		r := trainLogregModelsRegularized(models, dc)
-		models = append(models, r...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v regularized models\n", len(r))
-		}
+	dc, err := reader.Read()
+	if err != nil {
+		log.Println("error when getting the data.container from the reader,", err)
 	}
-	return
+	return dc
 }
 
-// trainSvmModels returns an array of modelContainers
-// with the trained svm models specified to be trained.
-// You specify which models to train by setting the trainSvm flags.
+// trainFamilyModels returns an array of modelContainers
+// with the trained models of the model family passed in.
+// You specify which stages to train by setting the specific, comb,
+// select, trans and reg flags.
 //
-func trainSvmModels(dc data.Container) (models ml.ModelContainers) {
-	if !*trainSvm {
-		return
-	}
+func trainFamilyModels(f *modelFamily, dc data.Container) (models ml.ModelContainers) {
 	if *verbose {
-		fmt.Println("training svm models")
+		fmt.Printf("training %v models\n", f.name)
 	}
-
-	if *trainSpecific {
-		if *verbose {
-			fmt.Println("\ttraining specific")
-		}
-		s := specificSvmModels(dc)
-		models = append(models, s...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v specific models\n", len(s))
-		}
+	if len(f.hyperparameters()) == 0 {
+		log.Printf("no hyperparameters to train %v models", f.name)
+		return
 	}
 
-	if *combinations > 0 {
+	stage := func(name string, train func() ml.ModelContainers) {
 		if *verbose {
-			fmt.Println("\ttraining combinations")
+			fmt.Printf("\n\ttraining %v\n", name)
 		}
-		c := trainSvmModelsByFeatureCombination(dc)
-		models = append(models, c...)
+		t := train()
+		models = append(models, t...)
 		if *verbose {
-			fmt.Printf("\n\tDone, trained %v combination models\n", len(c))
+			fmt.Printf("\n\tDone, trained %v %v models\n", len(t), name)
 		}
 	}
 
+	if *trainSpecific {
+		stage("specific", func() ml.ModelContainers { return specificModels(f, dc) })
+	}
+	if combinationsEnabled() {
+		stage("combination", func() ml.ModelContainers { return combinationModels(f, dc) })
+	}
+	if *featureSelection != "" {
+		stage("feature selection", func() ml.ModelContainers {
+			return selectFeatures(dc, f.name, func(dc data.Container, features []int) (*ml.ModelContainer, error) {
+				return featureModel(f, dc, f.hyperparameters()[0], features)
+			})
+		})
+	}
 	if *trainTransforms {
-		if *verbose {
-			fmt.Println("\ttraining transforms")
-		}
-		t := trainSvmModelsWithTransform(models, dc)
-		models = append(models, t...)
-		if *verbose {
-			fmt.Printf("\n\tDone, trained %v transformation models\n", len(t))
-		}
+		stage("transformed", func() ml.ModelContainers { return transformModels(f, models, dc) })
+	}
+	if *trainRegularized && f.regularizable {
+		stage("regularized", func() ml.ModelContainers { return regularizedModels(f, models, dc) })
 	}
-
 	return
 }
 
-// trainLinregModelsByFeatureCombination returns:
-// * an array of linearRegression models
-// It makes a model for every combinations of features present in the data.
-// Each feature corresponds to a column in the data set.
+// specificModels returns the models of the family passed in trained on
+// each of the specificFeatures, with the default hyperparameters.
 //
-func trainLinregModelsByFeatureCombination(dc data.Container) ml.ModelContainers {
-	if *combinations > 0 {
-		return linregCombinations(dc, *combinations)
+func specificModels(f *modelFamily, dc data.Container) (models ml.ModelContainers) {
+
+	fits.begin(f.name+" specific", len(specificFeatures))
+	defer fits.end()
+
+	for _, c := range specificFeatures {
+		mi := f.hyperparameters()[0]
+		mi.Features = c.features
+		name := f.name + " " + c.name
+		mc, err := trainOnce("specific", mi, dc, func(context.Context) (*ml.ModelContainer, error) {
+			mc, err := f.container(mi, dc)
+			if err != nil {
+				return nil, err
+			}
+			mc.Name = name
+			return mc, nil
+		})
+		if err != nil {
+			continue
+		}
+		models = append(models, mc)
 	}
-	return nil
+	return
 }
 
-// trainLogregModelsByFeatureCombination returns:
-// * an array of linearRegression models
-// It makes a model for every combinations of features present in the data.
-// Each feature corresponds to a column in the data set.
+// featureModel returns a model container of the family passed in with the
+// hyperparameters of mi trained on the features passed in.
 //
-func trainLogregModelsByFeatureCombination(dc data.Container) ml.ModelContainers {
-	if *combinations > 0 {
-		return logregCombinations(dc, *combinations)
-	}
-	return nil
+func featureModel(f *modelFamily, dc data.Container, mi modelInfo, features []int) (*ml.ModelContainer, error) {
+	mi.Features = features
+	return trainOnce("features", mi, dc, func(context.Context) (*ml.ModelContainer, error) {
+		return f.container(mi, dc)
+	})
 }
 
-// trainSvmModelsByFeatureCombination returns:
-// * an array of svm models
-// It makes a model for every combinations of features present in the data.
+// combinationModels returns:
+// * an array of models of the family passed in
+// It makes a model for every combinations of features present in the data
+// and every hyperparameter setting of the family.
 // Each feature corresponds to a column in the data set.
 //
-func trainSvmModelsByFeatureCombination(dc data.Container) ml.ModelContainers {
-	if *combinations > 0 {
-		return svmCombinations(dc, *combinations)
-	}
-	return nil
-}
-
-// trainLinregModelsWithTransform returns:
-//   * an array of linearRegression models
-//   * an array of used features vectors.
-//
-func trainLinregModelsWithTransform(models ml.ModelContainers, dc data.Container) (transModels ml.ModelContainers) {
-
-	return trainLinregModelsWithNDTransformFuncs(models, dc, transformArray(*transformDimension), *transformDimension)
-}
-
-// trainLogregModelsWithTransform returns:
-//   * an array of linearRegression models
-//   * an array of used features vectors.
-//
-func trainLogregModelsWithTransform(models ml.ModelContainers, dc data.Container) (transModels ml.ModelContainers) {
-
-	return trainLogregModelsWithNDTransformFuncs(models, dc, transformArray(*transformDimension), *transformDimension)
-}
-
-// trainSvmModelsWithTransform returns:
-//   * an array of svm models
-//   * an array of used features vectors.
-//
-func trainSvmModelsWithTransform(models ml.ModelContainers, dc data.Container) (transModels ml.ModelContainers) {
-
-	return trainSvmModelsWithNDTransformFuncs(models, dc, transformArray(*transformDimension), *transformDimension)
-}
-
-// transformArray returns an array of transform functions with respect to the dimension passed in.
-func transformArray(dim int) []func([]float64) ([]float64, error) {
-	switch dim {
-	case 2:
-		return transform.Funcs2D()
-	case 3:
-		return transform.Funcs3D()
-	case 4:
-		return transform.Funcs4D()
-	case 5:
-		return transform.Funcs5D()
-	default:
-		log.Printf("transformed dimension not supported %v", *transformDimension)
+func combinationModels(f *modelFamily, dc data.Container) (models ml.ModelContainers) {
+	combs, err := featureCombinations(dc.Features, *combinations)
+	if err != nil {
+		log.Println(err)
 		return nil
 	}
-}
+	settings := f.hyperparameters()
 
-//trainLinregModelsWithNDTransformFuncs returns
-//   * an array of linearRegression models
-//   * an array of used features vectors
-// Models are created as follows:
-// Data is filtered with respect to the 'candidateFeatures' vector and the 'dimension' param.
-// We generate a vector of combinations of the candidateFeatures vector.
-// Each combination has the size of the size of 'dimention'.
-// Each (combination, transform function) pair is a specific model.
-//
-func trainLinregModelsWithNDTransformFuncs(models ml.ModelContainers, dc data.Container, funcs []func([]float64) ([]float64, error), dimension int) (transModels ml.ModelContainers) {
+	if *verbose {
+		fmt.Printf("\truning %v %v combinations\n", f.name, len(combs))
+	}
 
-	for _, m := range models {
-		if m == nil {
-			continue
-		}
+	fits.begin(f.name+" combinations", len(combs)*len(settings))
+	defer fits.end()
 
-		fd := dc.FilterWithPredict(m.Features)
-		index := 0
-		format := "linreg %dD %v transformed %d"
-		for i, f := range funcs {
-			if lr, err := trainLinregModelWithTransform(fd, f); err == nil {
-				name := fmt.Sprintf(format, dimension, m.Features, index)
-				mc := ml.NewModelContainer(lr, name, m.Features)
-				mc.TransformDimension = dimension
-				mc.TransformID = i
-				transModels = append(transModels, mc)
-				index++
-			} else {
-				if *verbose {
-					fmt.Printf("trainLinregModels With %vD TransformFuncs - %v %v\n", dimension, i, err)
-				}
+	for _, c := range combs {
+		for _, mi := range settings {
+			if interrupted() {
+				return
+			}
+			if mc, err := featureModel(f, dc, mi, c); err == nil {
+				models = append(models, mc)
 			}
 		}
 	}
-	fmt.Printf("size of transModel %v", len(transModels))
 	return
 }
 
-//trainLogregModelsWithNDTransformFuncs returns
-//   * an array of linearRegression models
-//   * an array of used features vectors
+// transformModels returns
+//   * an array of models of the family passed in
 // Models are created as follows:
-// Data is filtered with respect to the 'candidateFeatures' vector and the 'dimension' param.
-// We generate a vector of combinations of the candidateFeatures vector.
-// Each combination has the size of the size of 'dimention'.
-// Each (combination, transform function) pair is a specific model.
+// Each model passed in is trained again with every transform function of
+// the dimension defined by the dim flag.
+// Each (model, transform function) pair is a specific model.
 //
-func trainLogregModelsWithNDTransformFuncs(models ml.ModelContainers, dc data.Container, funcs []func([]float64) ([]float64, error), dimension int) (transModels ml.ModelContainers) {
-
-	for _, m := range models {
-		if m == nil {
-			continue
-		}
+func transformModels(f *modelFamily, models ml.ModelContainers, dc data.Container) (transModels ml.ModelContainers) {
 
-		fd := dc.FilterWithPredict(m.Features)
-		index := 0
-		format := "logreg %dD %v transformed %d epochs-%v"
-		for i, f := range funcs {
-			if lr, err := trainLogregModelWithTransform(fd, f); err == nil {
-				name := fmt.Sprintf(format, dimension, m.Features, index, lr.Epochs)
-				if *verbose {
-					fmt.Printf("\r%v", name)
-				}
-				mc := ml.NewModelContainer(lr, name, m.Features)
-				mc.TransformDimension = dimension
-				mc.TransformID = i
-				transModels = append(transModels, mc)
-				index++
-			}
-		}
-	}
-	return
-}
+	dimension := *transformDimension
+	funcs := transformArray(dimension)
 
-//trainSvmModelsWithNDTransformFuncs returns
-//   * an array of svm models
-//   * an array of used features vectors
-// Models are created as follows:
-// Data is filtered with respect to the 'candidateFeatures' vector and the 'dimension' param.
-// We generate a vector of combinations of the candidateFeatures vector.
-// Each combination has the size of the size of 'dimention'.
-// Each (combination, transform function) pair is a specific model.
-//
-func trainSvmModelsWithNDTransformFuncs(models ml.ModelContainers, dc data.Container, funcs []func([]float64) ([]float64, error), dimension int) (transModels ml.ModelContainers) {
+	fits.begin(f.name+" transforms", len(models)*len(funcs))
+	defer fits.end()
 
 	for _, m := range models {
+		if interrupted() {
+			break
+		}
 		if m == nil {
 			continue
 		}
 
-		fd := dc.FilterWithPredict(m.Features)
-		index := 0
-		format := "svm %dD %v k %v T %v transformed %d"
-		for i, f := range funcs {
-			// todo(santiaago): should pass the model to copy all params from it.
-			if svm, err := trainSvmModelWithTransform(fd, f); err == nil {
-				name := fmt.Sprintf(format, dimension, m.Features, svm.K, svm.T, index)
+		base := ModelInfoFromModel(m)
+		for i := range funcs {
+			mi := base
+			mi.TransformDimension = Dimension(dimension)
+			mi.TransformID = i
+			mc, err := trainOnce("transform", mi, dc, func(context.Context) (*ml.ModelContainer, error) {
+				return f.container(mi, dc)
+			})
+			if err != nil {
 				if *verbose {
-					fmt.Printf("\r%v", name)
+					fmt.Printf("%v with %vD transform %v, %v\n", m.Name, dimension, i, err)
 				}
-				mc := ml.NewModelContainer(svm, name, m.Features)
-				mc.TransformDimension = dimension
-				mc.TransformID = i
-				transModels = append(transModels, mc)
-				index++
+				continue
 			}
+			transModels = append(transModels, mc)
 		}
 	}
 	return
 }
 
-// trainLinregModelWithTransform returns a linear model and an error if it fails to learn.
-// It uses the data passed as param and a transformation function.
-// todo(santiago): export transform function type from ml.
-//
-func trainLinregModelWithTransform(data [][]float64, f func([]float64) ([]float64, error)) (*linreg.LinearRegression, error) {
-	var err error
-	lr := linreg.NewLinearRegression()
-	lr.InitializeFromData(data)
-	lr.TransformFunction = f
-	if err = lr.ApplyTransformation(); err != nil {
-		return nil, err
-	}
-	err = lr.Learn()
-	return lr, err
-}
-
-// trainLogregModelWithTransform returns a linear model and an error if it fails to learn.
-// It uses the data passed as param and a transformation function.
+// regularizedModels returns the best regularized model for each
+// model of the family passed in.
+// The regularization parameter lambda = 10^-k is chosen by cross validation error
+// among all the k values of the regK range.
+// The cross validation error for every k is written to regularization.<family>.md
+// once the training is over, see writeRegularizations.
 //
-func trainLogregModelWithTransform(data [][]float64, f func([]float64) ([]float64, error)) (*logreg.LogisticRegression, error) {
-	var err error
-	lr := logreg.NewLogisticRegression()
-	lr.InitializeFromData(data)
-	lr.TransformFunction = f
-	if err = lr.ApplyTransformation(); err != nil {
-		return nil, err
-	}
+func regularizedModels(f *modelFamily, models ml.ModelContainers, dc data.Container) (regModels ml.ModelContainers) {
 
-	err = lr.Learn()
-	return lr, err
-}
+	fits.begin(f.name+" regularized", len(models))
+	defer fits.end()
 
-// trainSvmModelWithTransform returns a svm model and an error if it fails to learn.
-// It uses the data passed as param and a transformation function.
-//
-func trainSvmModelWithTransform(data [][]float64, f func([]float64) ([]float64, error)) (*svm.SVM, error) {
-	var err error
-	svm := svm.NewSVM()
-	svm.InitializeFromData(data)
-	svm.TransformFunction = f
-	svm.K = *svmK
-	svm.Lambda = *svmLambda
-	svm.T = *svmT
-
-	if err = svm.ApplyTransformation(); err != nil {
-		return nil, err
+	ks, err := intRange(*regK)
+	if err != nil {
+		log.Println(err)
+		return
 	}
 
-	err = svm.Learn()
-	return svm, err
-}
-
-// trainLinregModelsRegularized returns an array of models that are better in sample with regularized
-// option than the normal linear regression option.
-// to do this we go through all trained models and try
-// them with regularization if the in sample error
-// is lower append it to the list of models.
-//
-func trainLinregModelsRegularized(models ml.ModelContainers) (regModels ml.ModelContainers) {
+	var curves []regularizationCurve
 
 	for i, m := range models {
+		if interrupted() {
+			break
+		}
 		if m == nil {
 			continue
 		}
-		lr, ok := m.Model.(*linreg.LinearRegression)
-		if !ok {
+		base := ModelInfoFromModel(m)
+		if base.Regularized {
 			continue
 		}
+		base.Regularized = true
+		base.K = 0
+
 		if *verbose {
 			fmt.Printf("\rtraining regularized model %v %v/%v\n", m.Name, i, len(models))
 		}
-		if nlr, err := linregWithRegularization(lr); err == nil && nlr != nil {
-			name := fmt.Sprintf("%v regularized k %v", m.Name, nlr.K)
-			regModels = append(regModels, ml.NewModelContainer(nlr, name, m.Features))
-		} else if err != nil {
+		fd := dc.FilterWithPredict(m.Features)
+		w := rowWeights(dc)
+
+		learnK := func(k int) learner {
+			mi := base
+			mi.K = k
+			return func(fd [][]float64, w []float64) (ml.Model, error) {
+				return f.fit(mi, fd, w)
+			}
+		}
+
+		// curve is only set when the model is trained, not when it is restored.
+		var curve regularizationCurve
+		mc, err := trainOnce(regularizedStage(), base, dc, func(ctx context.Context) (*ml.ModelContainer, error) {
+			var err error
+			if curve, err = regularizationSweep(ctx, m.Name, fd, w, ks, learnK); err != nil {
+				return nil, err
+			}
+			mi := base
+			mi.K = curve.best().K
+			return f.container(mi, dc)
+		})
+		if err != nil {
 			log.Printf("cannot regularized model: %v, %v\n", m.Name, err)
+			continue
+		}
+		if len(curve.Points) > 0 {
+			curves = append(curves, curve)
 		}
+		regModels = append(regModels, mc)
 	}
+	regularizations[f.name] = append(regularizations[f.name], curves...)
 	return
 }
 
-// trainLogregModelsRegularized returns the best regularized logreg model for
-// each logreg model passed in.
+// specificFeatures are the feature sets of the specific models
+// trained by each model family, with their names.
 //
-func trainLogregModelsRegularized(models ml.ModelContainers, dc data.Container) (regModels ml.ModelContainers) {
-
-	var ok bool
-
-	for i, m := range models {
-		var lr *logreg.LogisticRegression
-		if lr, ok = m.Model.(*logreg.LogisticRegression); !ok {
-			continue
-		}
-		if lr.IsRegularized { // skip models that are already regularized<
-			continue
-		}
-		if *verbose {
-			fmt.Printf("\rtraining regularized model %v %v/%v\n", m.Name, i, len(models))
-		}
-		fd := dc.FilterWithPredict(m.Features)
-
-		for k := -5; k < 5; k++ {
-			if *verbose {
-				fmt.Printf("\rregularizing model %v/%v with k:%v\n", i, len(models), k)
-			}
-			var nlr *logreg.LogisticRegression
-			if nlr = logregFromK(k, fd, lr); nlr == nil {
-				continue
-			}
-
-			// todo(santiaago) clean this up
-			nlr.Wn = nlr.WReg
-			nlr.IsRegularized = true
-			name := fmt.Sprintf("%v regularized k %v", m.Name, k)
-			name += fmt.Sprintf(" epochs %v", nlr.Epochs)
+var specificFeatures = []struct {
+	features []int
+	name     string
+}{
+	{
+		[]int{passengerIndexSex, passengerIndexAge},
+		"Sex Age",
+	},
+	{
+		[]int{passengerIndexAge, passengerIndexPclass},
+		"PClass Age",
+	},
+
+	{
+		[]int{passengerIndexSex, passengerIndexPclass},
+		"PClass Sex",
+	},
+	{
+		[]int{passengerIndexSex, passengerIndexAge, passengerIndexPclass},
+		"Sex Age PClass",
+	},
+}
 
-			mc := ml.NewModelContainer(nlr, name, m.Features)
-			regModels = append(regModels, mc)
-		}
+// featureCombinations returns all the combinations of the features passed in
+// with respect to the comb param.
+// comb is either a size 'n', a range of sizes 'from:to' or 'all' for every
+// size from 1 to the number of features.
+//
+func featureCombinations(features []int, comb string) ([][]int, error) {
+	from, to, err := combinationSizes(comb, len(features))
+	if err != nil {
+		return nil, err
 	}
-	return
+	return itertools.CombinationsRange(features, from, to), nil
 }
 
-// logregFromK return a logistic regression model
-// based on the k parameter passed in and the logreg passed in.
+// combinationsEnabled returns true if the comb flag describes combinations
+// to train. An empty flag or a size of 0 disables the combinations.
 //
-func logregFromK(k int, fd [][]float64, lr *logreg.LogisticRegression) *logreg.LogisticRegression {
-	nlr := logreg.NewLogisticRegression()
-	nlr.InitializeFromData(fd)
+func combinationsEnabled() bool {
+	return *combinations != "" && *combinations != "0"
+}
 
-	if lr.HasTransform {
-		nlr.TransformFunction = lr.TransformFunction
-		nlr.ApplyTransformation()
+// combinationSizes returns the range of combination sizes described by comb.
+// n is the number of features available. The sizes above n are left out, it
+// is an error if they all are.
+//
+func combinationSizes(comb string, n int) (from, to int, err error) {
+	if comb == "all" {
+		return 1, n, nil
+	}
+	bounds := strings.Split(comb, ":")
+	if len(bounds) > 2 {
+		return 0, 0, fmt.Errorf("invalid combination range %v", comb)
+	}
+	if from, err = strconv.Atoi(bounds[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid combination size %v, %v", bounds[0], err)
+	}
+	to = from
+	if len(bounds) == 2 {
+		if to, err = strconv.Atoi(bounds[1]); err != nil {
+			return 0, 0, fmt.Errorf("invalid combination size %v, %v", bounds[1], err)
+		}
+	}
+	if from < 1 || to < from {
+		return 0, 0, fmt.Errorf("invalid combination range %v", comb)
+	}
+	if from > n {
+		return 0, 0, fmt.Errorf("invalid combination range %v, there are only %v features", comb, n)
+	}
+	if to > n {
+		to = n
 	}
+	return
+}
 
-	nlr.K = k
-	if err := nlr.LearnRegularized(); err != nil {
-		log.Printf("error calling logreg.LearnRegularized, %v\n", err)
+// transformArray returns an array of transform functions with respect to the dimension passed in.
+func transformArray(dim int) []func([]float64) ([]float64, error) {
+	switch dim {
+	case 2:
+		return transform.Funcs2D()
+	case 3:
+		return transform.Funcs3D()
+	case 4:
+		return transform.Funcs4D()
+	case 5:
+		return transform.Funcs5D()
+	case 0:
+		return nil
+	default:
+		log.Printf("transformed dimension not supported %v", dim)
 		return nil
 	}
-	return nlr
+}
+
+// regularizedStage returns the training stage of the regularized models,
+// which depends on the k values and the folds used to choose k.
+//
+func regularizedStage() string {
+	return fmt.Sprintf("regularized %v folds %v", *regK, *folds)
 }
 
 // updateModels re-trains all the models passed in.
+// Models restored from an exported state are only initialized with the data.
 //
 func updateModels(dc data.Container, models ml.ModelContainers) (trainedModels ml.ModelContainers) {
 	if *verbose {
 		fmt.Printf("updating models\n")
 	}
 	for _, mc := range models {
-		if lr, ok := mc.Model.(*linreg.LinearRegression); ok {
-			if *verbose {
-				fmt.Printf("\tlinear regression model\n")
-			}
-			fd := dc.FilterWithPredict(mc.Features)
-			lr.InitializeFromData(fd)
-			if lr.HasTransform {
-				lr.TransformFunction = transformArray(mc.TransformDimension)[mc.TransformID]
-				lr.ApplyTransformation()
-			}
-			if !lr.IsRegularized {
-				if err := lr.Learn(); err != nil {
-					log.Printf("unable to train model %v\n", mc.Name)
-					continue
-				}
-				trainedModels = append(trainedModels, mc)
-			} else {
-				if err := lr.LearnWeightDecay(); err != nil {
-					log.Printf("error calling lr.LearnWeightDecay, %v\n", err)
-					continue
-				}
-				trainedModels = append(trainedModels, mc)
-			}
-		} else if lr, ok := mc.Model.(*logreg.LogisticRegression); ok {
-			if *verbose {
-				fmt.Printf("\tlogistic regression model")
-			}
-			fd := dc.FilterWithPredict(mc.Features)
-			lr.InitializeFromData(fd)
-			if lr.HasTransform {
-				lr.TransformFunction = transformArray(mc.TransformDimension)[mc.TransformID]
-				lr.ApplyTransformation()
-			}
-			if !lr.IsRegularized {
-				if err := lr.Learn(); err != nil {
-					log.Printf("unable to train model %v\n", mc.Name)
-					continue
-				}
-				trainedModels = append(trainedModels, mc)
-			} else {
-				if err := lr.LearnRegularized(); err != nil {
-					log.Printf("error calling logreg.LearnRegularized, %v\n", err)
-					continue
-				}
-				trainedModels = append(trainedModels, mc)
-			}
-		} else if svm, ok := mc.Model.(*svm.SVM); ok {
-			if *verbose {
-				fmt.Printf("\tSVM model %+v\n", svm)
-				fmt.Printf("\tfeatures %v\n", mc.Features)
-			}
-			fd := dc.FilterWithPredict(mc.Features)
-			if *svmKOverride {
-				if *verbose {
-					fmt.Printf("\toverriding K %v\n", *svmK)
-				}
-				svm.K = *svmK
-			}
-			if *svmTOverride {
-				if *verbose {
-					fmt.Printf("\toverriding T %v\n", *svmTOverride)
-				}
-				svm.T = *svmT
-			}
-			if *svmLambdaOverride {
-				if *verbose {
-					fmt.Printf("\toverriding L %v\n", *svmLambda)
-				}
-				svm.Lambda = *svmLambda
-			}
-			svm.InitializeFromData(fd)
-			if svm.HasTransform {
-				svm.TransformFunction = transformArray(mc.TransformDimension)[mc.TransformID]
-				svm.ApplyTransformation()
-			}
-			if *verbose {
-				fmt.Printf("\tSVM model after import %+v\n", svm)
+		f := familyOfModel(mc.Model)
+		if f == nil {
+			continue
+		}
+		if *verbose {
+			fmt.Printf("\t%v model %v\n", f.name, mc.Name)
+		}
+		mi := ModelInfoFromModel(mc)
+		fd, w := dc.FilterWithPredict(mc.Features), rowWeights(dc)
+		if f.learned != nil && f.learned(mc.Model) {
+			// the learned state is set back after the initialization,
+			// which can reset it, as when restoring a checkpoint.
+			state, err := f.marshal(mc.Model)
+			if err == nil {
+				err = f.restore(mc.Model, mi, fd, w, state)
 			}
-			fmt.Printf("SVM before Wn %v\n", svm.Wn)
-			if err := svm.Learn(); err != nil {
-				log.Printf("unable to train model %v\n", mc.Name)
+			if err != nil {
+				log.Printf("unable to restore model %v, %v\n", mc.Name, err)
 				continue
 			}
-			fmt.Printf("SVM after Wn %v\n", svm.Wn)
-			if *verbose {
-				fmt.Printf("SVM Wn %v\n", svm.Wn)
-				fmt.Printf("SVM Vector Size %v\n", svm.VectorSize)
-				fmt.Printf("SVM Has transform %v\n", svm.HasTransform)
-				fmt.Printf("SVM Training points %v\n", svm.TrainingPoints)
-				fmt.Printf("SVM Lambda %v\n", svm.Lambda)
-				fmt.Printf("SVM Eta %v\n", svm.Eta)
-				fmt.Printf("SVM K %v\n", svm.K)
-				fmt.Printf("SVM T %v\n", svm.T)
-			}
+			mc.Model = withResample(f, mc.Model, mi, fd, w)
 			trainedModels = append(trainedModels, mc)
+			continue
+		}
+		if err := f.initialize(mc.Model, fd); err != nil {
+			log.Printf("unable to initialize model %v, %v\n", mc.Name, err)
+			continue
+		}
+		if w != nil {
+			if err := f.weigh(mc.Model, w); err != nil {
+				log.Printf("unable to weigh model %v, %v\n", mc.Name, err)
+				continue
+			}
+		}
+		if err := f.learn(mc.Model, mi); err != nil {
+			log.Printf("unable to train model %v, %v\n", mc.Name, err)
+			continue
 		}
+		mc.Model = withResample(f, mc.Model, mi, fd, w)
+		trainedModels = append(trainedModels, mc)
 	}
 	return
 }
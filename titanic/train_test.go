@@ -1,8 +1,13 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/santiaago/ml"
 	"github.com/santiaago/ml/data"
 	"github.com/santiaago/ml/svm"
 )
@@ -124,3 +129,94 @@ func equal2D(a, b [][]float64) bool {
 	}
 	return true
 }
+
+func TestCombinationSizes(t *testing.T) {
+	tests := []struct {
+		comb     string
+		from, to int
+		fail     bool
+	}{
+		{"3", 3, 3, false},
+		{"all", 1, 7, false},
+		{"2:4", 2, 4, false},
+		{"5:9", 5, 7, false},
+		{"7", 7, 7, false},
+		{"8", 0, 0, true},
+		{"8:9", 0, 0, true},
+		{"0", 0, 0, true},
+		{"4:2", 0, 0, true},
+		{"1:2:3", 0, 0, true},
+		{"a", 0, 0, true},
+	}
+	for _, tt := range tests {
+		from, to, err := combinationSizes(tt.comb, 7)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("combinationSizes(%v) = %v, %v, want an error", tt.comb, from, to)
+			}
+			continue
+		}
+		if err != nil || from != tt.from || to != tt.to {
+			t.Errorf("combinationSizes(%v) = %v, %v, %v, want %v, %v", tt.comb, from, to, err, tt.from, tt.to)
+		}
+	}
+}
+
+func TestCombinationsEnabled(t *testing.T) {
+	defer func(c string) { *combinations = c }(*combinations)
+	tests := []struct {
+		comb string
+		want bool
+	}{
+		{"", false},
+		{"0", false},
+		{"1", true},
+		{"all", true},
+	}
+	for _, tt := range tests {
+		*combinations = tt.comb
+		if got := combinationsEnabled(); got != tt.want {
+			t.Errorf("combinationsEnabled() with comb %q = %v, want %v", tt.comb, got, tt.want)
+		}
+	}
+}
+
+func TestExportImportLearnedState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(export bool) { *canExportModels = export }(*canExportModels)
+	*canExportModels = true
+
+	dc := smallContainer()
+	x := dc.Filter([]int{0, 1})
+	for _, f := range families {
+		if f.model == bagging {
+			continue
+		}
+		mi := f.hyperparameters()[0]
+		mi.Features = []int{0, 1}
+		mc, err := f.container(mi, dc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, f.name+".json")
+		exportModels(ml.ModelContainers{mc}, path)
+		imported := updateModels(dc, importModels(path))
+		if len(imported) != 1 || !f.learned(imported[0].Model) {
+			t.Errorf("%v model imported without its learned state", f.name)
+			continue
+		}
+		want, err := f.predict(mc.Model, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := f.predict(imported[0].Model, x)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("imported %v model predicts %v, %v, want %v", f.name, got, err, want)
+		}
+	}
+}
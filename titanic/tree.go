@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/santiaago/kaggle/classify"
+	"github.com/santiaago/kaggle/tree"
+	"github.com/santiaago/ml"
+)
+
+func init() {
+	registerFamily(&modelFamily{
+		model:   decisionTree,
+		name:    "tree",
+		enabled: trainTree,
+		params: []hyperparameter{
+			{"depth", "maximum depth of the tree."},
+			{"minLeaf", "minimum number of training points in a leaf."},
+			{"criterion", "impurity criterion of the splits: gini or entropy."},
+		},
+		is: func(m ml.Model) bool {
+			_, ok := m.(*tree.Tree)
+			return ok
+		},
+		hyperparameters: treeHyperparameters,
+		newModel: func(mi modelInfo) ml.Model {
+			t := tree.NewTree()
+			t.TransformFunction, t.HasTransform = mi.transformFunction()
+			t.Folds = *folds
+			t.MaxDepth = mi.intParam("depth", t.MaxDepth)
+			t.MinLeaf = mi.intParam("minLeaf", t.MinLeaf)
+			t.Criterion = mi.param("criterion", t.Criterion)
+			return t
+		},
+		initialize: func(m ml.Model, fd [][]float64) error {
+			t := m.(*tree.Tree)
+			if err := t.InitializeFromData(fd); err != nil {
+				return err
+			}
+			if t.HasTransform {
+				return t.ApplyTransformation()
+			}
+			return nil
+		},
+		weigh: func(m ml.Model, w []float64) error {
+			return m.(*tree.Tree).SetWeights(w)
+		},
+		learn: func(m ml.Model, mi modelInfo) error {
+			return m.(*tree.Tree).Learn()
+		},
+		predict: func(m ml.Model, x [][]float64) ([]float64, error) {
+			return m.(*tree.Tree).Predictions(x)
+		},
+		scores: func(m ml.Model, x [][]float64) ([]float64, error) {
+			t := m.(*tree.Tree)
+			return classify.Scores(t.Probability, &t.Data, x)
+		},
+		probabilistic: true,
+		describe: func(m ml.Model, mi *modelInfo) {
+			t := m.(*tree.Tree)
+			mi.setParam("depth", t.MaxDepth)
+			mi.setParam("minLeaf", t.MinLeaf)
+			mi.setParam("criterion", t.Criterion)
+		},
+		marshal: func(m ml.Model) (json.RawMessage, error) {
+			return json.Marshal(m.(*tree.Tree).Root)
+		},
+		unmarshal: func(m ml.Model, mi modelInfo, state json.RawMessage) error {
+			t := m.(*tree.Tree)
+			t.ResetErrors()
+			return json.Unmarshal(state, &t.Root)
+		},
+		learned: func(m ml.Model) bool {
+			return m.(*tree.Tree).Root != nil
+		},
+	})
+}
+
+// treeHyperparameters returns a model info for every combination of the
+// values of the treeDepth, treeMinLeaf and treeCriterion flags.
+//
+func treeHyperparameters() (mis []modelInfo) {
+	depths, err := intRange(*treeDepth)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	minLeaves, err := intRange(*treeMinLeaf)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	criteria, err := stringList(*treeCriterion, tree.Gini, tree.Entropy)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, d := range depths {
+		for _, l := range minLeaves {
+			for _, c := range criteria {
+				mi := modelInfo{Model: decisionTree}
+				mi.setParam("depth", d)
+				mi.setParam("minLeaf", l)
+				mi.setParam("criterion", c)
+				mis = append(mis, mi)
+			}
+		}
+	}
+	return
+}
+
+// treeFeatureNames returns the names of the coordinates of the points of
+// the model container passed in: x0 followed by the passenger columns of
+// its features. Transformed points have no names.
+//
+func treeFeatureNames(mc *ml.ModelContainer) []string {
+	if mc.TransformDimension > int(NOT) {
+		return nil
+	}
+	names := []string{"x0"}
+	for _, f := range mc.Features {
+		name := fmt.Sprintf("column %v", f)
+		if f < len(passengerColumns) {
+			name = passengerColumns[f]
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// writeTrees writes a text dump of every decision tree of the models
+// passed in, so that the learned splits can be inspected.
+//
+func writeTrees(models ml.ModelContainers, name string) {
+	var trees []*ml.ModelContainer
+	for _, m := range models {
+		if m == nil {
+			continue
+		}
+		if _, ok := m.Model.(*tree.Tree); ok {
+			trees = append(trees, m)
+		}
+	}
+	if len(trees) == 0 {
+		return
+	}
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + partialName(name))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, m := range trees {
+		t := m.Model.(*tree.Tree)
+		fmt.Fprintf(writer, "decision tree of model: %v\n", m.Name)
+		fmt.Fprintf(writer, "depth = %v\tEin = %f\tEcv = %f\n", t.Depth(), t.Ein(), t.Ecv())
+		fmt.Fprintf(writer, "%v\n", t.Dump(treeFeatureNames(m)))
+	}
+	if err := writer.Flush(); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+// balancedClasses weighs each class by the inverse of its frequency, see classWeights.
+const balancedClasses = "balanced"
+
+// sampleWeightsByID are the weights of the file of the sampleWeights flag
+// by passenger id, loaded by checkWeighting.
+var sampleWeightsByID map[string]float64
+
+// sampleWeight returns the weight of the passenger of the id passed in
+// in the file of the sampleWeights flag, 1 if the flag is empty or the
+// passenger is not in the file.
+// The file is a csv file with a header and the columns PassengerId,Weight.
+func sampleWeight(id string) float64 {
+	if w, ok := sampleWeightsByID[id]; ok {
+		return w
+	}
+	return 1
+}
+
+// readSampleWeights returns the weights of the passengers of the csv file
+// passed in by passenger id.
+func readSampleWeights(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sample weights %v, %v", path, err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read sample weights %v, %v", path, err)
+	}
+	weights := make(map[string]float64)
+	for i, record := range records {
+		// the first line is the header.
+		if i == 0 {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("invalid line %v of sample weights %v", i+1, path)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight %v of passenger %v in %v", record[1], record[0], path)
+		}
+		weights[strings.TrimSpace(record[0])] = w
+	}
+	return weights, nil
+}
+
+// classWeights returns the weights of the passengers of dc that died and
+// survived described by the classWeight flag: 'balanced' weighs each class
+// by n / (2 n_class), so that both classes weigh the same, and two comma
+// separated values 'died,survived' weigh them explicitly. An empty flag
+// weighs both by 1.
+func classWeights(dc data.Container) (died, survived float64, err error) {
+	switch *classWeight {
+	case "":
+		return 1, 1, nil
+	case balancedClasses:
+		var n [2]float64
+		for _, row := range dc.Data {
+			n[labelClass(row[dc.Predict])]++
+		}
+		died, survived = 1, 1
+		if n[0] > 0 {
+			died = (n[0] + n[1]) / (2 * n[0])
+		}
+		if n[1] > 0 {
+			survived = (n[0] + n[1]) / (2 * n[1])
+		}
+		return
+	}
+	weights, err := floatList(*classWeight)
+	if err != nil || len(weights) != 2 || weights[0] < 0 || weights[1] < 0 || weights[0]+weights[1] == 0 {
+		return 0, 0, fmt.Errorf("invalid class weight %v, expected %v or 'died,survived'", *classWeight, balancedClasses)
+	}
+	return weights[0], weights[1], nil
+}
+
+// labelClass returns 1 for the label of the passengers that survived and 0 for
+// the others.
+func labelClass(y float64) int {
+	if y == 1 {
+		return 1
+	}
+	return 0
+}
+
+// checkWeighting returns an error if the weighting flags are invalid and
+// loads the weights of the file of the sampleWeights flag, see sampleWeight.
+// It is called before reading the training data.
+func checkWeighting() error {
+	if _, _, err := classWeights(data.Container{}); err != nil {
+		return err
+	}
+	sampleWeightsByID = nil
+	if *sampleWeightsPath == "" {
+		return nil
+	}
+	weights, err := readSampleWeights(*sampleWeightsPath)
+	if err != nil {
+		return err
+	}
+	sampleWeightsByID = weights
+	return nil
+}
+
+// rowWeights returns the weight of each row of dc: the weight of its class,
+// see classWeights, times its sample weight, see sampleWeight.
+// It returns nil when the rows are not weighted so that the models are
+// trained as without weights.
+func rowWeights(dc data.Container) []float64 {
+	if *classWeight == "" && *sampleWeightsPath == "" {
+		return nil
+	}
+	died, survived, err := classWeights(dc)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	w := make([]float64, len(dc.Data))
+	for i, row := range dc.Data {
+		w[i] = died
+		if labelClass(row[dc.Predict]) == 1 {
+			w[i] = survived
+		}
+		w[i] *= row[passengerIndexWeight]
+	}
+	return w
+}
+
+// weighted returns the model info passed in with the weighting of the
+// training rows described by the weighting flags, and whether the weights
+// are applied by resampling the rows, see modelFamily.resampled.
+func weighted(mi modelInfo) modelInfo {
+	mi.ClassWeight = *classWeight
+	mi.SampleWeights = *sampleWeightsPath
+	mi.Resampled = false
+	if mi.ClassWeight != "" || mi.SampleWeights != "" {
+		if f := familyOf(mi.Model); f != nil {
+			mi.Resampled = f.resampled
+		}
+	}
+	return mi
+}
+
+// splitWeights returns the weights of the rows that are not in the fold
+// passed in and of the rows that are in it, see splitFold.
+// Both are nil if w is nil.
+func splitWeights(w []float64, fold []int) (train, validation []float64) {
+	if w == nil {
+		return nil, nil
+	}
+	in := make(map[int]bool)
+	for _, i := range fold {
+		in[i] = true
+	}
+	for i, v := range w {
+		if in[i] {
+			validation = append(validation, v)
+		} else {
+			train = append(train, v)
+		}
+	}
+	return
+}
+
+// resampled returns the points and the labels passed in resampled by the
+// weights w, see resampleRows.
+func resampled(xn [][]float64, yn []float64, w []float64) ([][]float64, []float64, error) {
+	rows, err := resampleRows(w)
+	if err != nil {
+		return nil, nil, err
+	}
+	x := make([][]float64, len(rows))
+	y := make([]float64, len(rows))
+	for k, i := range rows {
+		x[k], y[k] = xn[i], yn[i]
+	}
+	return x, y, nil
+}
+
+// resampleRows returns the rows of a weighted resample of the same size of
+// rows weighted by w, drawn systematically: each row appears about
+// len(w) * w[i] / sum(w) times. It is used by the models of the ml
+// package, which have no weights.
+// The resample only approximates a weighted fit: the rows are drawn in
+// order, so the resample depends on the order of the rows, and a row whose
+// share of the total weight is below 1 / len(w) may not be drawn at all.
+// It returns an error if the rows weigh nothing.
+func resampleRows(w []float64) ([]int, error) {
+	var total float64
+	for _, v := range w {
+		total += v
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("unable to resample %v rows of total weight %v", len(w), total)
+	}
+	rows := make([]int, 0, len(w))
+	var cumulative float64
+	i := 0
+	for k := range w {
+		draw := (float64(k) + 0.5) * total / float64(len(w))
+		for i < len(w)-1 && cumulative+w[i] <= draw {
+			cumulative += w[i]
+			i++
+		}
+		rows = append(rows, i)
+	}
+	return rows, nil
+}
+
+// resampledModel is a model of a family without weights trained on a
+// resample of its training rows by weight, see modelFamily.resampled.
+// The errors of such a model, computed by the ml package on its training
+// points, would be the ones of the resample, where the heavy rows are
+// repeated, so resampledModel computes them on the original rows: Ein on
+// all of them and Ecv on each fold by a model trained on a resample of the
+// other folds only.
+type resampledModel struct {
+	ml.Model
+	f  *modelFamily
+	mi modelInfo
+	fd [][]float64 // original training rows, the label last.
+	w  []float64   // weight of each row.
+
+	ein, ecv float64
+	einOnce  sync.Once
+	ecvOnce  sync.Once
+}
+
+// withResample returns the model passed in, trained by the family on a
+// resample of fd by w, as a resampledModel. It returns the model as it is
+// if w is nil or the family has weights.
+func withResample(f *modelFamily, m ml.Model, mi modelInfo, fd [][]float64, w []float64) ml.Model {
+	if w == nil || !f.resampled {
+		return m
+	}
+	return &resampledModel{Model: m, f: f, mi: mi, fd: fd, w: w}
+}
+
+// unwrap returns the model trained on the resample of a resampledModel or
+// the model passed in. The families with resampled models unwrap the models
+// they are passed.
+func unwrap(m ml.Model) ml.Model {
+	if r, ok := m.(*resampledModel); ok {
+		return r.Model
+	}
+	return m
+}
+
+// Ein returns the in sample error on the original rows, 1 if the model
+// cannot predict them.
+func (r *resampledModel) Ein() float64 {
+	r.einOnce.Do(func() {
+		r.ein = 1
+		x, y := splitPredict(r.fd)
+		predictions, err := r.Model.Predictions(x)
+		if err != nil {
+			log.Printf("unable to compute the in sample error, %v", err)
+			return
+		}
+		var wrong int
+		for i := range predictions {
+			if predictions[i] != y[i] {
+				wrong++
+			}
+		}
+		r.ein = float64(wrong) / float64(len(r.fd))
+	})
+	return r.ein
+}
+
+// Ecv returns the cross validation error on the original rows, each fold
+// predicted by a model trained on a resample of the other folds, 1 if the
+// models cannot be trained.
+func (r *resampledModel) Ecv() float64 {
+	r.ecvOnce.Do(func() {
+		var err error
+		r.ecv, err = crossValidationError(r.fd, r.w, *folds, func(fd [][]float64, w []float64) (ml.Model, error) {
+			return r.f.fit(r.mi, fd, w)
+		})
+		if err != nil {
+			log.Printf("unable to compute the cross validation error, %v", err)
+			r.ecv = 1
+		}
+	})
+	return r.ecv
+}
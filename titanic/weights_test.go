@@ -0,0 +1,217 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/santiaago/ml"
+	"github.com/santiaago/ml/data"
+)
+
+func TestResampleRows(t *testing.T) {
+	tests := []struct {
+		w    []float64
+		want []int
+	}{
+		{[]float64{1, 1, 1, 1}, []int{0, 1, 2, 3}},
+		{[]float64{3, 1, 0, 0}, []int{0, 0, 0, 1}},
+		{[]float64{0, 1}, []int{1, 1}},
+		// the share of row 0 is below 1 / 4, it is not drawn.
+		{[]float64{0.1, 1, 1, 1}, []int{1, 2, 2, 3}},
+		// the same weights in another order give another resample.
+		{[]float64{1, 1, 1, 0.1}, []int{0, 1, 1, 2}},
+	}
+	for _, tt := range tests {
+		if got, err := resampleRows(tt.w); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resampleRows(%v) = %v, %v, want %v", tt.w, got, err, tt.want)
+		}
+	}
+	if rows, err := resampleRows([]float64{0, 0}); err == nil {
+		t.Errorf("resampleRows of rows that weigh nothing = %v, want an error", rows)
+	}
+}
+
+func TestSplitWeights(t *testing.T) {
+	train, validation := splitWeights([]float64{1, 2, 3, 4, 5}, []int{1, 3})
+	if !reflect.DeepEqual(train, []float64{1, 3, 5}) || !reflect.DeepEqual(validation, []float64{2, 4}) {
+		t.Errorf("splitWeights = %v, %v, want [1 3 5], [2 4]", train, validation)
+	}
+	if train, validation := splitWeights(nil, []int{1}); train != nil || validation != nil {
+		t.Errorf("splitWeights(nil) = %v, %v, want nil, nil", train, validation)
+	}
+}
+
+func TestClassWeights(t *testing.T) {
+	// three passengers died and one survived.
+	dc := weightedContainer([]float64{-1, -1, 1, -1}, []float64{1, 1, 1, 1})
+	tests := []struct {
+		classWeight    string
+		died, survived float64
+		fail           bool
+	}{
+		{"", 1, 1, false},
+		{"balanced", 4.0 / 6, 2, false},
+		{"1,3", 1, 3, false},
+		{" 0.5 , 2 ", 0.5, 2, false},
+		{"0,1", 0, 1, false},
+		{"x", 0, 0, true},
+		{"1", 0, 0, true},
+		{"1,2,3", 0, 0, true},
+		{"-1,2", 0, 0, true},
+		{"0,0", 0, 0, true},
+		{"a,b", 0, 0, true},
+	}
+	defer setFlags(t, map[string]string{"classWeight": ""})()
+	for _, tt := range tests {
+		*classWeight = tt.classWeight
+		died, survived, err := classWeights(dc)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("classWeights(%q) = %v, %v, want an error", tt.classWeight, died, survived)
+			}
+			continue
+		}
+		if err != nil || math.Abs(died-tt.died) > 1e-9 || math.Abs(survived-tt.survived) > 1e-9 {
+			t.Errorf("classWeights(%q) = %v, %v, %v, want %v, %v", tt.classWeight, died, survived, err, tt.died, tt.survived)
+		}
+	}
+
+	// a class without passengers weighs 1.
+	*classWeight = balancedClasses
+	if died, survived, err := classWeights(weightedContainer([]float64{-1, -1}, []float64{1, 1})); err != nil || died != 0.5 || survived != 1 {
+		t.Errorf("balanced classWeights without survivors = %v, %v, %v, want 0.5, 1", died, survived, err)
+	}
+}
+
+func TestRowWeights(t *testing.T) {
+	dc := weightedContainer([]float64{-1, -1, 1, -1}, []float64{1, 1, 2, 0.5})
+	tests := []struct {
+		classWeight, sampleWeights string
+		want                       []float64
+	}{
+		{"", "", nil},
+		{"", "weights.csv", []float64{1, 1, 2, 0.5}},
+		{"balanced", "", []float64{4.0 / 6, 4.0 / 6, 4, 2.0 / 6}},
+		{"1,3", "", []float64{1, 1, 6, 0.5}},
+	}
+	defer setFlags(t, map[string]string{"classWeight": "", "sampleWeights": ""})()
+	for _, tt := range tests {
+		*classWeight, *sampleWeightsPath = tt.classWeight, tt.sampleWeights
+		got := rowWeights(dc)
+		if (got == nil) != (tt.want == nil) || len(got) != len(tt.want) {
+			t.Errorf("rowWeights(%q, %q) = %v, want %v", tt.classWeight, tt.sampleWeights, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+				t.Errorf("rowWeights(%q, %q) = %v, want %v", tt.classWeight, tt.sampleWeights, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCheckWeighting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "weights")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	valid := filepath.Join(dir, "valid.csv")
+	ioutil.WriteFile(valid, []byte("PassengerId,Weight\n1,3\n2, 0.5\n"), 0644)
+	negative := filepath.Join(dir, "negative.csv")
+	ioutil.WriteFile(negative, []byte("PassengerId,Weight\n1,-3\n"), 0644)
+
+	defer setFlags(t, map[string]string{"classWeight": "", "sampleWeights": ""})()
+	defer func() { sampleWeightsByID = nil }()
+	tests := []struct {
+		classWeight, sampleWeights string
+		fail                       bool
+	}{
+		{"", "", false},
+		{"balanced", valid, false},
+		{"x", "", true},
+		{"", negative, true},
+		{"", filepath.Join(dir, "missing.csv"), true},
+	}
+	for _, tt := range tests {
+		*classWeight, *sampleWeightsPath = tt.classWeight, tt.sampleWeights
+		if err := checkWeighting(); (err != nil) != tt.fail {
+			t.Errorf("checkWeighting(%q, %q) = %v, want an error %v", tt.classWeight, tt.sampleWeights, err, tt.fail)
+		}
+	}
+
+	*classWeight, *sampleWeightsPath = "", valid
+	if err := checkWeighting(); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]float64{"1": 3, "2": 0.5, "3": 1} {
+		if got := sampleWeight(id); got != want {
+			t.Errorf("sampleWeight(%v) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+// weightedContainer returns a data container of passengers with the labels
+// and the sample weights passed in.
+func weightedContainer(labels, weights []float64) data.Container {
+	dc := data.Container{Predict: passengerIndexSurvived}
+	for i, y := range labels {
+		row := make([]float64, passengerIndexWeight+1)
+		row[passengerIndexSurvived] = y
+		row[passengerIndexWeight] = weights[i]
+		dc.Data = append(dc.Data, row)
+	}
+	return dc
+}
+
+func TestResampledModelErrors(t *testing.T) {
+	dc := smallContainer()
+	fd := dc.FilterWithPredict([]int{0, 1})
+	w := make([]float64, len(fd))
+	for i := range w {
+		// the rows labeled +1 are drawn three times as often.
+		w[i] = 1
+		if fd[i][2] > 0 {
+			w[i] = 3
+		}
+	}
+	f := familyNamed("linreg")
+	mi := f.hyperparameters()[0]
+	mi.Features = []int{0, 1}
+	m, err := f.fit(mi, fd, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.(*resampledModel); !ok || familyOfModel(m) != f {
+		t.Fatalf("weighted linreg model of type %T, want a resampled linreg model", m)
+	}
+
+	x, y := splitPredict(fd)
+	predictions, err := f.predict(m, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wrong float64
+	for i := range y {
+		if predictions[i] != y[i] {
+			wrong++
+		}
+	}
+	if ein := m.Ein(); ein != wrong/float64(len(fd)) {
+		t.Errorf("Ein = %v, want %v on the original rows", ein, wrong/float64(len(fd)))
+	}
+	ecv, err := crossValidationError(fd, w, *folds, func(fd [][]float64, w []float64) (ml.Model, error) {
+		return f.fit(mi, fd, w)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Ecv() != ecv {
+		t.Errorf("Ecv = %v, want %v on the original rows", m.Ecv(), ecv)
+	}
+}
@@ -41,7 +41,7 @@ func writeRanking(models ml.ModelContainers, name, title, errTitle string, model
 
 	createTempFolder(*tempPath)
 
-	file, err := os.Create(*tempPath + name)
+	file, err := os.Create(*tempPath + partialName(name))
 	defer file.Close()
 
 	if err != nil {
@@ -50,6 +50,9 @@ func writeRanking(models ml.ModelContainers, name, title, errTitle string, model
 
 	writer := bufio.NewWriter(file)
 
+	if partialResults {
+		title += fmt.Sprintf(" (partial: %v)", partialReason())
+	}
 	if _, err := writer.WriteString(title + "\n"); err != nil {
 		log.Fatalln(err)
 	}
@@ -59,11 +62,134 @@ func writeRanking(models ml.ModelContainers, name, title, errTitle string, model
 			continue
 		}
 
-		line := fmt.Sprintf("%v\t\t%v = %f\tmodel: %v\n", i, errTitle, modelError(m), m.Name)
+		line := fmt.Sprintf("%v\t\t%v = %f\tmodel: %v", i, errTitle, modelError(m), m.Name)
+		// ensembles have no model family to describe them in a model file.
+		if familyOfModel(m.Model) == nil {
+			line += " (not exported)"
+		}
+		line += "\n"
 		if _, err := writer.WriteString(line); err != nil {
 			log.Fatalln(err)
 		}
 		writer.Flush()
 	}
+
+	// fits that exceeded their time budget are ranked last.
+	for _, t := range timeouts.list() {
+		line := fmt.Sprintf("-\t\t%v = timed out after %v\tmodel: %v (%v)\n", errTitle, *fitTimeout, t.Name, t.Stage)
+		if _, err := writer.WriteString(line); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	writer.Flush()
+}
+
+// writeRegularizationCurves writes the cross validation error of
+// every k tried for each regularization curve passed in.
+func writeRegularizationCurves(curves []regularizationCurve, name string) {
+	if len(curves) == 0 {
+		return
+	}
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + partialName(name))
+	defer file.Close()
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	for _, c := range curves {
+		best := c.best()
+		lines := []string{
+			fmt.Sprintf("regularization curve of model: %v\n", c.Name),
+			fmt.Sprintf("best k = %v\tlambda = %g\tEcv = %f\n", best.K, best.Lambda, best.Ecv),
+			"k\tlambda\tEcv\n",
+		}
+		for _, p := range c.Points {
+			lines = append(lines, fmt.Sprintf("%v\t%g\t%f\n", p.K, p.Lambda, p.Ecv))
+		}
+		lines = append(lines, "\n")
+		for _, line := range lines {
+			if _, err := writer.WriteString(line); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
+	writer.Flush()
+}
+
+// writeReliabilities writes the reliability tables passed in to the file
+// name in the temp folder.
+//
+func writeReliabilities(tables []reliability, name string) {
+	if len(tables) == 0 {
+		return
+	}
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + partialName(name))
+	defer file.Close()
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	for _, t := range tables {
+		lines := []string{
+			fmt.Sprintf("reliability of model: %v\tcalibration: %v\n", t.Name, t.Calibration),
+			fmt.Sprintf("Brier score = %f\n", t.Brier),
+			"probability\tcount\tpredicted\tobserved\n",
+		}
+		for _, b := range t.Bins {
+			lines = append(lines, fmt.Sprintf("%.2f-%.2f\t%v\t%f\t%f\n", b.From, b.To, b.Count, b.Predicted, b.Observed))
+		}
+		lines = append(lines, "\n")
+		for _, line := range lines {
+			if _, err := writer.WriteString(line); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
+	writer.Flush()
+}
+
+// writeThresholds writes the decision thresholds passed in, tuned for the
+// metric passed in, to the file name in the temp folder.
+//
+func writeThresholds(tuned []tunedThreshold, metric, name string) {
+	if len(tuned) == 0 {
+		return
+	}
+
+	createTempFolder(*tempPath)
+
+	file, err := os.Create(*tempPath + partialName(name))
+	defer file.Close()
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	lines := []string{
+		fmt.Sprintf("decision thresholds maximizing the %v of the out of fold scores\n", metric),
+		"default\t" + metric + "\tthreshold\t" + metric + "\tmodel\n",
+	}
+	for _, t := range tuned {
+		lines = append(lines, fmt.Sprintf("%f\t%f\t%f\t%f\t%v\n", t.Default, t.Before, t.Threshold, t.After, t.Name))
+	}
+	for _, line := range lines {
+		if _, err := writer.WriteString(line); err != nil {
+			log.Fatalln(err)
+		}
+	}
 	writer.Flush()
 }
@@ -0,0 +1,306 @@
+// Package tree implements a CART decision tree binary classifier.
+package tree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/santiaago/kaggle/classify"
+)
+
+// Criteria measuring the impurity of the labels of a node.
+const (
+	Gini    = "gini"
+	Entropy = "entropy"
+)
+
+// Tree is a CART decision tree.
+// Each inner node splits the points on a single coordinate and each leaf
+// predicts the majority label of its training points, by weight if the
+// training points have weights.
+type Tree struct {
+	classify.Data
+	MaxDepth  int    // maximum depth of the tree, the root has depth 0.
+	MinLeaf   int    // minimum number of training points in a leaf.
+	Criterion string // Gini or Entropy.
+	Features  int    // number of coordinates tried at each split, chosen at random, more if none of them splits. 0 tries all of them.
+	Seed      int64  // seed of the choice of the coordinates tried at each split.
+	Root      *Node  // learned tree.
+
+	rand *rand.Rand
+}
+
+// Node is a node of a decision tree.
+// Inner nodes send the points with x[Feature] <= Threshold to Left and
+// the others to Right. Leaves have no children.
+type Node struct {
+	Feature   int     `json:",omitempty"`
+	Threshold float64 `json:",omitempty"`
+	Left      *Node   `json:",omitempty"`
+	Right     *Node   `json:",omitempty"`
+	Label     float64 // majority label of the training points of the node.
+	Samples   int     // number of training points of the node.
+	Positives int     // number of training points labeled +1.
+
+	// weights of the training points of the node and of those labeled +1,
+	// only set when the training points have weights.
+	Weight         float64 `json:",omitempty"`
+	PositiveWeight float64 `json:",omitempty"`
+}
+
+// IsLeaf returns true if the node has no children.
+func (n *Node) IsLeaf() bool {
+	return n.Left == nil || n.Right == nil
+}
+
+// NewTree returns a decision tree of depth 5 with at least 5 points per
+// leaf, the Gini criterion and 10 folds of cross validation.
+func NewTree() *Tree {
+	return &Tree{
+		Data:      classify.Data{Folds: 10},
+		MaxDepth:  5,
+		MinLeaf:   5,
+		Criterion: Gini,
+	}
+}
+
+// Learn grows the tree on the training points.
+func (t *Tree) Learn() error {
+	t.ResetErrors()
+	t.Root = nil
+	if t.MaxDepth < 0 {
+		return fmt.Errorf("invalid max depth %v", t.MaxDepth)
+	}
+	if t.MinLeaf < 1 {
+		return fmt.Errorf("invalid min samples per leaf %v", t.MinLeaf)
+	}
+	if t.Criterion != Gini && t.Criterion != Entropy {
+		return fmt.Errorf("unknown criterion %v", t.Criterion)
+	}
+	if t.Features < 0 {
+		return fmt.Errorf("invalid number of features per split %v", t.Features)
+	}
+	if len(t.Xn) == 0 {
+		return fmt.Errorf("no training points")
+	}
+	rows := make([]int, len(t.Xn))
+	for i := range rows {
+		rows[i] = i
+	}
+	t.rand = rand.New(rand.NewSource(t.Seed))
+	t.Root = t.grow(rows, 0)
+	return nil
+}
+
+// impurity returns the impurity of a set of points of weight n with a
+// weight p of them labeled +1.
+func (t *Tree) impurity(p, n float64) float64 {
+	if n <= 0 {
+		return 0
+	}
+	q := p / n
+	if t.Criterion == Entropy {
+		h := 0.0
+		for _, r := range []float64{q, 1 - q} {
+			if r > 0 {
+				h -= r * math.Log2(r)
+			}
+		}
+		return h
+	}
+	return 2 * q * (1 - q)
+}
+
+// grow returns the subtree learned on the training points of rows.
+func (t *Tree) grow(rows []int, depth int) *Node {
+	n := &Node{Samples: len(rows)}
+	var weight, positive float64
+	for _, i := range rows {
+		w := t.Weight(i)
+		weight += w
+		if t.Yn[i] == 1 {
+			n.Positives++
+			positive += w
+		}
+	}
+	if t.Wn != nil {
+		n.Weight, n.PositiveWeight = weight, positive
+	}
+	n.Label = -1
+	if 2*positive >= weight {
+		n.Label = 1
+	}
+	if depth >= t.MaxDepth || n.Positives == 0 || n.Positives == n.Samples || n.Samples < 2*t.MinLeaf {
+		return n
+	}
+
+	best := t.impurity(positive, weight)
+	split := false
+	sorted := make([]int, len(rows))
+	for k, j := range t.candidates() {
+		// as long as no split is found more coordinates are tried.
+		if t.Features > 0 && k >= t.Features && split {
+			break
+		}
+		copy(sorted, rows)
+		sort.SliceStable(sorted, func(a, b int) bool { return t.Xn[sorted[a]][j] < t.Xn[sorted[b]][j] })
+		var left, leftPositive float64 // weights of the first k points and of their positives.
+		for k := 1; k < len(sorted); k++ {
+			w := t.Weight(sorted[k-1])
+			left += w
+			if t.Yn[sorted[k-1]] == 1 {
+				leftPositive += w
+			}
+			lo, hi := t.Xn[sorted[k-1]][j], t.Xn[sorted[k]][j]
+			if lo == hi || k < t.MinLeaf || len(sorted)-k < t.MinLeaf {
+				continue
+			}
+			right := weight - left
+			impurity := (left*t.impurity(leftPositive, left) + right*t.impurity(positive-leftPositive, right)) / weight
+			// only splits that decrease the impurity are kept.
+			if impurity < best-1e-12 {
+				best = impurity
+				n.Feature = j
+				n.Threshold = (lo + hi) / 2
+				split = true
+			}
+		}
+	}
+	if !split {
+		return n
+	}
+	var left, right []int
+	for _, i := range rows {
+		if t.Xn[i][n.Feature] <= n.Threshold {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+	n.Left = t.grow(left, depth+1)
+	n.Right = t.grow(right, depth+1)
+	return n
+}
+
+// candidates returns the coordinates tried at a split, in a random order
+// if only Features of them are tried. x0 = 1 is left out in that case as
+// it never splits the points.
+func (t *Tree) candidates() []int {
+	if t.Features == 0 {
+		all := make([]int, t.VectorSize)
+		for j := range all {
+			all[j] = j
+		}
+		return all
+	}
+	c := t.rand.Perm(t.VectorSize - 1)
+	for k := range c {
+		c[k]++
+	}
+	return c
+}
+
+// Predict returns the label of the leaf of x.
+func (t *Tree) Predict(x []float64) float64 {
+	if n := t.leaf(x); n != nil {
+		return n.Label
+	}
+	return 1
+}
+
+// Probability returns the fraction of the training points of the leaf of x
+// labeled +1, by weight if the training points have weights.
+func (t *Tree) Probability(x []float64) float64 {
+	n := t.leaf(x)
+	if n == nil || n.Samples == 0 {
+		return 0.5
+	}
+	if n.Weight > 0 {
+		return n.PositiveWeight / n.Weight
+	}
+	return float64(n.Positives) / float64(n.Samples)
+}
+
+// leaf returns the leaf of x or nil if the tree is not trained.
+func (t *Tree) leaf(x []float64) *Node {
+	n := t.Root
+	if n == nil {
+		return nil
+	}
+	for !n.IsLeaf() {
+		if x[n.Feature] <= n.Threshold {
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	return n
+}
+
+// Predictions returns the predictions of the points passed in, without labels.
+func (t *Tree) Predictions(x [][]float64) ([]float64, error) {
+	return classify.Predictions(t, &t.Data, x)
+}
+
+// Ein returns the in sample error.
+func (t *Tree) Ein() float64 {
+	return t.CachedEin(func() float64 { return classify.Ein(t, &t.Data) })
+}
+
+// Ecv returns the cross validation error.
+func (t *Tree) Ecv() float64 {
+	return t.CachedEcv(func() float64 {
+		return classify.CrossValidation(&t.Data, func(d classify.Data) (classify.Classifier, error) {
+			c := *t
+			c.Data = d
+			return &c, c.Learn()
+		})
+	})
+}
+
+// Depth returns the depth of the learned tree.
+func (t *Tree) Depth() int {
+	var depth func(n *Node) int
+	depth = func(n *Node) int {
+		if n == nil || n.IsLeaf() {
+			return 0
+		}
+		l, r := depth(n.Left), depth(n.Right)
+		if l > r {
+			return l + 1
+		}
+		return r + 1
+	}
+	return depth(t.Root)
+}
+
+// Dump returns a text representation of the learned tree, one node per line.
+// names are the names of the coordinates of a point, x[j] is used for the
+// coordinates without a name.
+func (t *Tree) Dump(names []string) string {
+	name := func(j int) string {
+		if j < len(names) && names[j] != "" {
+			return names[j]
+		}
+		return fmt.Sprintf("x[%v]", j)
+	}
+	var b strings.Builder
+	var dump func(n *Node, indent string)
+	dump = func(n *Node, indent string) {
+		if n.IsLeaf() {
+			fmt.Fprintf(&b, "%v|--- label %v (samples %v, positives %v)\n", indent, n.Label, n.Samples, n.Positives)
+			return
+		}
+		fmt.Fprintf(&b, "%v|--- %v <= %g (samples %v, positives %v)\n", indent, name(n.Feature), n.Threshold, n.Samples, n.Positives)
+		dump(n.Left, indent+"|   ")
+		fmt.Fprintf(&b, "%v|--- %v > %g\n", indent, name(n.Feature), n.Threshold)
+		dump(n.Right, indent+"|   ")
+	}
+	if t.Root != nil {
+		dump(t.Root, "")
+	}
+	return b.String()
+}
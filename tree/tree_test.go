@@ -0,0 +1,75 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+)
+
+// points returns points labeled +1 when both coordinates are positive,
+// an interaction no single split captures.
+func points() (data [][]float64) {
+	for _, a := range []float64{-2, -1, 1, 2} {
+		for _, b := range []float64{-2, -1, 1, 2} {
+			y := -1.0
+			if a > 0 && b > 0 {
+				y = 1
+			}
+			data = append(data, []float64{a, b, y})
+		}
+	}
+	return
+}
+
+func TestTreeLearn(t *testing.T) {
+	for _, criterion := range []string{Gini, Entropy} {
+		tr := NewTree()
+		tr.Criterion = criterion
+		tr.MinLeaf = 1
+		if err := tr.InitializeFromData(points()); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Learn(); err != nil {
+			t.Fatal(err)
+		}
+		if ein := tr.Ein(); ein != 0 {
+			t.Errorf("%v: Ein = %v, want 0", criterion, ein)
+		}
+		if d := tr.Depth(); d != 2 {
+			t.Errorf("%v: depth = %v, want 2", criterion, d)
+		}
+		predictions, err := tr.Predictions([][]float64{{3, 3}, {3, -3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if predictions[0] != 1 || predictions[1] != -1 {
+			t.Errorf("%v: wrong predictions %v", criterion, predictions)
+		}
+		if p, q := tr.Probability([]float64{1, 3, 3}), tr.Probability([]float64{1, 3, -3}); p != 1 || q != 0 {
+			t.Errorf("%v: probabilities = %v %v, want 1 0", criterion, p, q)
+		}
+		if dump := tr.Dump([]string{"x0", "a", "b"}); !strings.Contains(dump, "a <= 0") || !strings.Contains(dump, "b <= 0") {
+			t.Errorf("%v: unexpected dump\n%v", criterion, dump)
+		}
+	}
+}
+
+func TestTreeLimits(t *testing.T) {
+	tr := NewTree()
+	tr.InitializeFromData(points())
+	tr.MaxDepth = 1
+	tr.MinLeaf = 1
+	if err := tr.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if d := tr.Depth(); d != 1 {
+		t.Errorf("depth = %v, want 1", d)
+	}
+	tr.MaxDepth = 5
+	tr.MinLeaf = 9
+	if err := tr.Learn(); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.Root.IsLeaf() {
+		t.Error("expected a single leaf with 16 points and at least 9 per leaf")
+	}
+}